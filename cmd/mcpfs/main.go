@@ -1,13 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/caffeinum/mcpfs/internal/config"
 	"github.com/caffeinum/mcpfs/internal/fs"
+	"github.com/caffeinum/mcpfs/internal/mcp"
+	"github.com/caffeinum/mcpfs/internal/oauth"
+	"github.com/caffeinum/mcpfs/internal/pool"
+	"github.com/caffeinum/mcpfs/internal/result"
 )
 
 var version = "dev"
@@ -18,6 +27,8 @@ func main() {
 		Short:   "mount mcp servers as a fuse filesystem",
 		Version: version,
 	}
+	rootCmd.PersistentFlags().String("config", "", "config directory (default: ~/.mcp/.config)")
+	rootCmd.PersistentFlags().String("profile", config.DefaultProfile(), "server set under <config>/profiles to use (default: $MCPFS_PROFILE)")
 
 	mountCmd := &cobra.Command{
 		Use:   "mount <mountpoint>",
@@ -26,7 +37,12 @@ func main() {
 		RunE:  runMount,
 	}
 	mountCmd.Flags().BoolP("foreground", "f", false, "run in foreground (always true for now)")
-	mountCmd.Flags().String("config", "", "config directory (default: ~/.mcp/.config)")
+	mountCmd.Flags().Int("head-lines", 20, "lines shown in .result.head")
+	mountCmd.Flags().Int("page-tokens", 4000, "approximate tokens per .result.pages entry")
+	mountCmd.Flags().Int("page-bytes", 0, "bytes per .result.pages entry (overrides --page-tokens)")
+	mountCmd.Flags().Int("history-limit", 100, "calls kept per tool in history/ (-1 disables)")
+	mountCmd.Flags().Bool("project", false, "trust and use the "+config.ProjectFile+" found in the current directory or its parents")
+	mountCmd.Flags().Bool("no-project", false, "ignore "+config.ProjectFile+" in the current directory and its parents")
 
 	umountCmd := &cobra.Command{
 		Use:   "umount <mountpoint>",
@@ -44,11 +60,53 @@ func main() {
 	addCmd.Flags().String("url", "", "http server url (for http transport)")
 
 	authCmd := &cobra.Command{
-		Use:   "auth <server> <token>",
+		Use:   "auth <server> [token]",
 		Short: "store auth token for a server",
-		Args:  cobra.ExactArgs(2),
+		Args:  cobra.RangeArgs(1, 2),
 		RunE:  runAuth,
 	}
+	authCmd.Flags().String("store", config.AuthStore(), "where to keep the token: file or keyring")
+	authCmd.Flags().Bool("oauth", false, "log in through the server's oauth flow in a browser")
+	authCmd.Flags().String("scope", "", "oauth scopes to request (default: what the server advertises)")
+
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "import servers from another mcp client's config",
+		Args:  cobra.NoArgs,
+		RunE:  runImport,
+	}
+	importCmd.Flags().String("from", config.SourceClaudeDesktop, "claude-desktop, claude-code, cursor, vscode or a json file")
+	importCmd.Flags().Bool("force", false, "replace servers that are already configured")
+	importCmd.Flags().String("store", config.AuthStore(), "where to keep lifted secrets: file or keyring")
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "print servers in another mcp client's config format",
+		Args:  cobra.NoArgs,
+		RunE:  runExport,
+	}
+	exportCmd.Flags().String("to", config.TargetJSON, "claude-desktop, claude-code, cursor, vscode or json")
+	exportCmd.Flags().Bool("resolve-auth", false, "write resolved secrets instead of ${auth.x} references")
+	exportCmd.Flags().Bool("proxy", false, "point every entry at 'mcpfs proxy <server>' instead")
+
+	proxyCmd := &cobra.Command{
+		Use:   "proxy <server>",
+		Short: "serve one configured server over stdio, for clients without mcpfs",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runProxy,
+	}
+
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "inspect the server config",
+	}
+	validateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "check servers.json and report every problem",
+		Args:  cobra.NoArgs,
+		RunE:  runValidate,
+	}
+	configCmd.AddCommand(validateCmd)
 
 	statusCmd := &cobra.Command{
 		Use:   "status",
@@ -62,7 +120,7 @@ func main() {
 		RunE:  runList,
 	}
 
-	rootCmd.AddCommand(mountCmd, umountCmd, addCmd, authCmd, statusCmd, listCmd)
+	rootCmd.AddCommand(mountCmd, umountCmd, addCmd, authCmd, importCmd, exportCmd, proxyCmd, configCmd, statusCmd, listCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -71,7 +129,39 @@ func main() {
 
 func runMount(cmd *cobra.Command, args []string) error {
 	mountpoint := args[0]
-	configDir, _ := cmd.Flags().GetString("config")
+	useProject, _ := cmd.Flags().GetBool("project")
+	noProject, _ := cmd.Flags().GetBool("no-project")
+	if useProject && noProject {
+		return fmt.Errorf("--project and --no-project can't be used together")
+	}
+
+	// a repo's .mcpfs.json adds its servers and may pick the profile, once
+	// the user has trusted it as it is now
+	var project *config.Project
+	var projectProfile string
+	if wd, err := os.Getwd(); err == nil && !noProject {
+		if path := config.FindProject(wd); path != "" {
+			if project, err = trustedProject(cmd, path, useProject); err != nil {
+				return err
+			}
+		}
+	}
+	if project != nil {
+		projectProfile = project.Profile
+	}
+
+	configDir, err := configDirFor(cmd, projectProfile)
+	if err != nil {
+		return err
+	}
+	headLines, _ := cmd.Flags().GetInt("head-lines")
+	pageTokens, _ := cmd.Flags().GetInt("page-tokens")
+	pageBytes, _ := cmd.Flags().GetInt("page-bytes")
+	historyLimit, _ := cmd.Flags().GetInt("history-limit")
+
+	if pageBytes == 0 {
+		pageBytes = result.TokensToBytes(pageTokens)
+	}
 
 	// try to create mountpoint, but don't fail if we can't (fskit may handle it)
 	os.MkdirAll(mountpoint, 0755)
@@ -79,10 +169,51 @@ func runMount(cmd *cobra.Command, args []string) error {
 	return fs.Mount(fs.MountOptions{
 		Mountpoint: mountpoint,
 		ConfigDir:  configDir,
+		Project:    project,
 		Foreground: true,
+		HeadLines:  headLines,
+		PageSize:   pageBytes,
+		History:    historyLimit,
 	})
 }
 
+// trustedProject loads the project file at path if it's trusted, trusting
+// it first when trust is set, and returns nil with a note otherwise.
+func trustedProject(cmd *cobra.Command, path string, trust bool) (*config.Project, error) {
+	project, err := config.LoadProject(path)
+	if err != nil {
+		return nil, err
+	}
+	// trust is shared by all profiles, since the project may pick one
+	base, _ := cmd.Flags().GetString("config")
+	trustDir, err := config.ProfileDir(base, "")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case trust:
+		if err := config.TrustProject(trustDir, project); err != nil {
+			return nil, err
+		}
+	case !config.ProjectTrusted(trustDir, project):
+		fmt.Fprintf(os.Stderr, "ignoring %s: it's new or changed since it was trusted; check it and mount with --project to use it\n", path)
+		return nil, nil
+	}
+	return project, nil
+}
+
+// configDirFor resolves --config and --profile into the directory a command
+// reads and writes. fallbackProfile is used when neither the flag nor
+// MCPFS_PROFILE names one.
+func configDirFor(cmd *cobra.Command, fallbackProfile string) (string, error) {
+	base, _ := cmd.Flags().GetString("config")
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile == "" {
+		profile = fallbackProfile
+	}
+	return config.ProfileDir(base, profile)
+}
+
 func runUmount(cmd *cobra.Command, args []string) error {
 	mountpoint := args[0]
 	if err := fs.Unmount(mountpoint); err != nil {
@@ -95,40 +226,63 @@ func runUmount(cmd *cobra.Command, args []string) error {
 func runAdd(cmd *cobra.Command, args []string) error {
 	name := args[0]
 	url, _ := cmd.Flags().GetString("url")
-	configDir, _ := cmd.Flags().GetString("config")
+	configDir, err := configDirFor(cmd, "")
+	if err != nil {
+		return err
+	}
 
 	cfg, err := config.Load(configDir)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
 
+	var srv *config.ServerConfig
 	if url != "" {
-		cfg.AddHTTPServer(name, url, map[string]string{
-			"Authorization": "Bearer ${auth.token}",
-		})
-		fmt.Printf("added http server: %s\n", name)
+		srv = &config.ServerConfig{
+			Transport: config.TransportHTTP,
+			URL:       url,
+			Headers:   map[string]string{"Authorization": "Bearer ${auth.token}"},
+		}
 	} else if len(args) > 1 {
-		command := args[1]
-		cmdArgs := args[2:]
-		cfg.AddStdioServer(name, command, cmdArgs, nil)
-		fmt.Printf("added stdio server: %s\n", name)
+		srv = &config.ServerConfig{
+			Transport: config.TransportStdio,
+			Command:   args[1],
+			Args:      args[2:],
+		}
 	} else {
 		return fmt.Errorf("must provide --url or command after --")
 	}
+	if err := cfg.Check(name, srv); err != nil {
+		return err
+	}
+	cfg.SetServer(name, srv)
 
 	if err := cfg.Save(); err != nil {
 		return fmt.Errorf("save config: %w", err)
 	}
+	fmt.Printf("added %s server: %s\n", srv.Transport, name)
 
 	return nil
 }
 
 func runAuth(cmd *cobra.Command, args []string) error {
 	server := args[0]
+	configDir, err := configDirFor(cmd, "")
+	if err != nil {
+		return err
+	}
+	store, _ := cmd.Flags().GetString("store")
+
+	if useOAuth, _ := cmd.Flags().GetBool("oauth"); useOAuth {
+		scope, _ := cmd.Flags().GetString("scope")
+		return runOAuth(configDir, server, store, scope)
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("must provide a token or --oauth")
+	}
 	token := args[1]
-	configDir, _ := cmd.Flags().GetString("config")
 
-	if err := config.SaveToken(configDir, server, token); err != nil {
+	if err := config.SaveTokenTo(configDir, server, token, store); err != nil {
 		return fmt.Errorf("save token: %w", err)
 	}
 
@@ -136,6 +290,301 @@ func runAuth(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runOAuth(configDir, server, store, scope string) error {
+	cfg, err := config.Load(configDir)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	srv, ok := cfg.GetServer(server)
+	if !ok {
+		return fmt.Errorf("server not found: %s", server)
+	}
+	if srv.Transport != config.TransportHTTP {
+		return fmt.Errorf("oauth needs an http server, %s is %s", server, srv.Transport)
+	}
+
+	flow := &oauth.Flow{
+		Scope: scope,
+		Open: func(authURL string) error {
+			fmt.Printf("open this url to authorize mcpfs:\n  %s\n", authURL)
+			openBrowser(authURL)
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	session, err := flow.Login(ctx, srv.URL)
+	if err != nil {
+		return fmt.Errorf("oauth: %w", err)
+	}
+
+	auth, err := config.LoadAuth(cfg.Dir(), server)
+	if err != nil {
+		return fmt.Errorf("load auth: %w", err)
+	}
+	session.Store(auth)
+	if err := config.SaveAuthTo(cfg.Dir(), server, auth, store); err != nil {
+		return fmt.Errorf("save auth: %w", err)
+	}
+
+	// make sure the token is actually sent
+	if _, ok := srv.Headers["Authorization"]; !ok {
+		updated := *srv
+		updated.Headers = map[string]string{"Authorization": "Bearer ${auth.token}"}
+		for k, v := range srv.Headers {
+			updated.Headers[k] = v
+		}
+		if err := cfg.Check(server, &updated); err != nil {
+			return err
+		}
+		cfg.SetServer(server, &updated)
+		if err := cfg.Save(); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+	}
+
+	fmt.Printf("authorized %s\n", server)
+	return nil
+}
+
+// openBrowser is best effort; the url is always printed too.
+func openBrowser(u string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	default:
+		cmd = exec.Command("xdg-open", u)
+	}
+	cmd.Start()
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	force, _ := cmd.Flags().GetBool("force")
+	store, _ := cmd.Flags().GetString("store")
+	configDir, err := configDirFor(cmd, "")
+	if err != nil {
+		return err
+	}
+
+	paths, err := config.ImportPaths(from)
+	if err != nil {
+		return err
+	}
+
+	// a client keeps servers in several places; later ones win
+	external := make(map[string]*config.ExternalServer)
+	var read []string
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if os.IsNotExist(err) && len(paths) > 1 {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read %s: %w", p, err)
+		}
+		servers, err := config.ParseExternalServers(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		for name, srv := range servers {
+			external[name] = srv
+		}
+		read = append(read, p)
+	}
+	if len(external) == 0 {
+		return fmt.Errorf("no mcp servers found in %v", paths)
+	}
+
+	imported := config.ImportServers(external)
+
+	cfg, err := config.Load(configDir)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	var added int
+	for _, imp := range imported {
+		if imp.Skipped != "" {
+			fmt.Printf("skipped %s: %s\n", imp.Name, imp.Skipped)
+			continue
+		}
+		if _, exists := cfg.GetServer(imp.Name); exists && !force {
+			fmt.Printf("skipped %s: already configured (use --force to replace)\n", imp.Name)
+			continue
+		}
+		if err := cfg.Check(imp.Name, imp.Server); err != nil {
+			fmt.Printf("skipped %s: %v\n", imp.Name, err)
+			continue
+		}
+
+		// secrets first, so the config never references values that aren't stored
+		if len(imp.Auth) > 0 {
+			auth, err := config.LoadAuth(cfg.Dir(), imp.Name)
+			if err != nil {
+				return fmt.Errorf("load auth for %s: %w", imp.Name, err)
+			}
+			auth.Apply(imp.Auth)
+			if err := config.SaveAuthTo(cfg.Dir(), imp.Name, auth, store); err != nil {
+				return fmt.Errorf("save auth for %s: %w", imp.Name, err)
+			}
+		}
+		cfg.SetServer(imp.Name, imp.Server)
+		added++
+
+		fmt.Printf("imported %s (%s)\n", imp.Name, imp.Server.Transport)
+		for _, key := range sortedKeys(imp.Auth) {
+			fmt.Printf("  moved %s to auth\n", key)
+		}
+		for _, note := range imp.Notes {
+			fmt.Printf("  note: %s\n", note)
+		}
+	}
+
+	if added == 0 {
+		return nil
+	}
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	fmt.Printf("imported %d of %d servers from %v\n", added, len(imported), read)
+	return nil
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	to, _ := cmd.Flags().GetString("to")
+	resolve, _ := cmd.Flags().GetBool("resolve-auth")
+	proxy, _ := cmd.Flags().GetBool("proxy")
+	configDir, err := configDirFor(cmd, "")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(configDir)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	opts := config.ExportOptions{ResolveAuth: resolve}
+	if proxy {
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("find mcpfs binary: %w", err)
+		}
+		opts.Proxy = []string{exe, "proxy"}
+		if base, _ := cmd.Flags().GetString("config"); base != "" {
+			opts.Proxy = append(opts.Proxy, "--config", base)
+		}
+		if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+			opts.Proxy = append(opts.Proxy, "--profile", profile)
+		}
+	}
+
+	data, notes, err := cfg.ExportServers(to, opts)
+	if err != nil {
+		return err
+	}
+	// notes go to stderr so the output can be redirected straight into a file
+	for _, note := range notes {
+		fmt.Fprintf(os.Stderr, "note: %s\n", note)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func runProxy(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	configDir, err := configDirFor(cmd, "")
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(configDir)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	if _, ok := cfg.GetServer(name); !ok {
+		return fmt.Errorf("server not found: %s", name)
+	}
+
+	p := pool.New(pool.PoolConfig{Config: cfg})
+	defer p.Close()
+
+	return mcp.Serve(context.Background(), os.Stdin, os.Stdout, name, &proxyBackend{cfg: cfg, pool: p, name: name})
+}
+
+// proxyBackend forwards to a pooled connection, applying the server's tool
+// policy the same way the mount does.
+type proxyBackend struct {
+	cfg  *config.Config
+	pool *pool.Pool
+	name string
+}
+
+func (b *proxyBackend) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	conn, err := b.pool.GetConnection(ctx, b.name)
+	if err != nil {
+		return nil, err
+	}
+	srv, _ := b.cfg.GetServer(b.name)
+
+	var tools []mcp.Tool
+	for _, tool := range conn.GetTools() {
+		if srv.ToolAllowed(tool.Name) == nil {
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+func (b *proxyBackend) CallTool(ctx context.Context, tool string, args map[string]any) (*mcp.ToolResult, error) {
+	srv, _ := b.cfg.GetServer(b.name)
+	if err := srv.CheckCall(tool, args); err != nil {
+		return nil, err
+	}
+	conn, err := b.pool.GetConnection(ctx, b.name)
+	if err != nil {
+		return nil, err
+	}
+	return conn.CallTool(ctx, tool, args)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	configDir, err := configDirFor(cmd, "")
+	if err != nil {
+		return err
+	}
+
+	problems := config.ValidateDir(configDir)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if config.HasFatal(problems) {
+		return fmt.Errorf("%d problems found", len(problems))
+	}
+
+	cfg, err := config.Load(configDir)
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+	fmt.Printf("%d servers ok, %d warnings\n", len(cfg.Names()), len(problems))
+	return nil
+}
+
 func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Println("server status:")
 	fmt.Println("  (mount filesystem first to see connection status)")
@@ -143,7 +592,10 @@ func runStatus(cmd *cobra.Command, args []string) error {
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	configDir, _ := cmd.Flags().GetString("config")
+	configDir, err := configDirFor(cmd, "")
+	if err != nil {
+		return err
+	}
 
 	cfg, err := config.Load(configDir)
 	if err != nil {
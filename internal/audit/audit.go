@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Log is an append-only jsonl record of every tool call made through the
+// mount, including refused ones.
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Server     string    `json:"server"`
+	Tool       string    `json:"tool"`
+	ArgsHash   string    `json:"argsHash"`
+	DurationMS int64     `json:"durationMs"`
+	IsError    bool      `json:"isError"`
+	Cached     bool      `json:"cached,omitempty"`
+	Denied     string    `json:"denied,omitempty"` // why the call never reached the server
+	PID        int       `json:"pid"`
+	UID        uint32    `json:"uid"`
+}
+
+// how much of the file Recent reads at a time
+var tailChunk = 64 * 1024
+
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// HashArgs fingerprints arguments without storing them. encoding/json sorts
+// map keys, so equal arguments hash equally.
+func HashArgs(args map[string]any) string {
+	if args == nil {
+		args = map[string]any{}
+	}
+	data, _ := json.Marshal(args)
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func (l *Log) Record(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal audit entry: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("create audit dir: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// Recent returns the last n lines of the log. it reads backwards from the
+// end, so it costs the same however long the log has grown.
+func (l *Log) Recent(n int) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		return []byte{}
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return []byte{}
+	}
+
+	// n lines need n+1 newlines in view: the last ends the file
+	var tail []byte
+	newlines := 0
+	for off := info.Size(); off > 0 && newlines <= n; {
+		size := min(int64(tailChunk), off)
+		off -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, off); err != nil {
+			return []byte{}
+		}
+		newlines += bytes.Count(chunk, []byte("\n"))
+		tail = append(chunk, tail...)
+	}
+
+	tail = bytes.TrimSuffix(tail, []byte("\n"))
+	if len(tail) == 0 {
+		return []byte{}
+	}
+	lines := bytes.Split(tail, []byte("\n"))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append(bytes.Join(lines, []byte("\n")), '\n')
+}
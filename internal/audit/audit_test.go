@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHashArgs(t *testing.T) {
+	a := HashArgs(map[string]any{"repo": "ourorg/mcpfs", "number": 1.0})
+	b := HashArgs(map[string]any{"number": 1.0, "repo": "ourorg/mcpfs"})
+	if a != b {
+		t.Error("expected hash to ignore key order")
+	}
+	if !strings.HasPrefix(a, "sha256:") || strings.Contains(a, "ourorg") {
+		t.Errorf("unexpected hash: %s", a)
+	}
+	if HashArgs(nil) != HashArgs(map[string]any{}) {
+		t.Error("expected nil and empty args to hash equally")
+	}
+}
+
+func TestRecordRecent(t *testing.T) {
+	log := New(filepath.Join(t.TempDir(), "audit", "audit.jsonl"))
+
+	if got := log.Recent(10); len(got) != 0 {
+		t.Errorf("expected empty log, got %q", got)
+	}
+
+	for i, tool := range []string{"a", "b", "c"} {
+		err := log.Record(Entry{
+			Time:   time.Now(),
+			Server: "@github/mcp",
+			Tool:   tool,
+			PID:    100 + i,
+			UID:    501,
+		})
+		if err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(log.Recent(2))), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	var e Entry
+	if err := json.Unmarshal([]byte(lines[1]), &e); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if e.Tool != "c" || e.PID != 102 || e.UID != 501 {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
+
+func TestRecentReadsBackwards(t *testing.T) {
+	defer func(n int) { tailChunk = n }(tailChunk)
+	tailChunk = 16
+
+	log := New(filepath.Join(t.TempDir(), "audit.jsonl"))
+	for i := 0; i < 50; i++ {
+		log.Record(Entry{Server: "@github/mcp", Tool: "t", PID: i})
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(log.Recent(3))), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("parse %q: %v", line, err)
+		}
+		if e.PID != 47+i {
+			t.Errorf("line %d: expected pid %d, got %d", i, 47+i, e.PID)
+		}
+	}
+
+	if got := strings.Count(string(log.Recent(100)), "\n"); got != 50 {
+		t.Errorf("expected all 50 lines, got %d", got)
+	}
+}
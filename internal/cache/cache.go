@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+// Cache memoizes tool results on disk, keyed by server, tool and canonical
+// arguments, so identical calls are answered across agents and remounts.
+type Cache struct {
+	dir   string
+	mu    sync.Mutex
+	stats map[string]*Stats // server -> counters
+}
+
+type Stats struct {
+	Hits   int64
+	Misses int64
+}
+
+type entry struct {
+	Server  string          `json:"server"`
+	Tool    string          `json:"tool"`
+	Expires time.Time       `json:"expires"`
+	Result  json.RawMessage `json:"result"`
+}
+
+func New(dir string) *Cache {
+	return &Cache{
+		dir:   dir,
+		stats: make(map[string]*Stats),
+	}
+}
+
+// Key hashes the call. encoding/json sorts map keys, which makes the
+// marshaled arguments canonical.
+func Key(server, tool string, args map[string]any) string {
+	if args == nil {
+		args = map[string]any{}
+	}
+	data, _ := json.Marshal([]any{server, tool, args})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) path(server, key string) string {
+	return filepath.Join(c.dir, config.SafeServerName(server), key+".json")
+}
+
+// Get returns a cached result that hasn't expired and counts the lookup.
+func (c *Cache) Get(server, tool string, args map[string]any) (*mcp.ToolResult, bool) {
+	res, ok := c.lookup(server, tool, args)
+
+	c.mu.Lock()
+	st := c.statsFor(server)
+	if ok {
+		st.Hits++
+	} else {
+		st.Misses++
+	}
+	c.mu.Unlock()
+
+	return res, ok
+}
+
+func (c *Cache) lookup(server, tool string, args map[string]any) (*mcp.ToolResult, bool) {
+	p := c.path(server, Key(server, tool, args))
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, false
+	}
+	if time.Now().After(e.Expires) {
+		os.Remove(p)
+		return nil, false
+	}
+
+	var res mcp.ToolResult
+	if err := json.Unmarshal(e.Result, &res); err != nil {
+		return nil, false
+	}
+	res.Raw = e.Result
+	return &res, true
+}
+
+// Put stores a result for ttl. error results are never cached.
+func (c *Cache) Put(server, tool string, args map[string]any, res *mcp.ToolResult, ttl time.Duration) error {
+	if ttl <= 0 || res.IsError {
+		return nil
+	}
+
+	raw := res.Raw
+	if len(raw) == 0 {
+		var err error
+		if raw, err = json.Marshal(res); err != nil {
+			return fmt.Errorf("marshal result: %w", err)
+		}
+	}
+
+	data, err := json.Marshal(entry{
+		Server:  server,
+		Tool:    tool,
+		Expires: time.Now().Add(ttl),
+		Result:  raw,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal cache entry: %w", err)
+	}
+
+	p := c.path(server, Key(server, tool, args))
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+
+	// write then rename so concurrent mounts never read half an entry. the
+	// temp name is unique so two writers of the same key don't share it.
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) Stats(server string) Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return *c.statsFor(server)
+}
+
+func (c *Cache) statsFor(server string) *Stats {
+	st, ok := c.stats[server]
+	if !ok {
+		st = &Stats{}
+		c.stats[server] = st
+	}
+	return st
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+func TestKeyCanonical(t *testing.T) {
+	a := Key("@github/mcp", "list_repos", map[string]any{"owner": "caffeinum", "per_page": 10.0})
+	b := Key("@github/mcp", "list_repos", map[string]any{"per_page": int64(10), "owner": "caffeinum"})
+	if a != b {
+		t.Error("expected key to ignore argument order and number representation")
+	}
+
+	if Key("@github/mcp", "list_repos", nil) != Key("@github/mcp", "list_repos", map[string]any{}) {
+		t.Error("expected nil and empty args to share a key")
+	}
+	if a == Key("@github/mcp", "search", map[string]any{"owner": "caffeinum", "per_page": 10.0}) {
+		t.Error("expected different tools to have different keys")
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir)
+	args := map[string]any{"owner": "caffeinum"}
+
+	if _, ok := c.Get("@github/mcp", "list_repos", args); ok {
+		t.Fatal("expected miss on empty cache")
+	}
+
+	res := &mcp.ToolResult{
+		Content: []mcp.ContentBlock{{Type: "text", Text: "mcpfs"}},
+		Raw:     json.RawMessage(`{"content":[{"type":"text","text":"mcpfs"}],"_meta":{"x":1}}`),
+	}
+	if err := c.Put("@github/mcp", "list_repos", args, res, time.Minute); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	// a fresh cache over the same dir, like a remount
+	c2 := New(dir)
+	got, ok := c2.Get("@github/mcp", "list_repos", args)
+	if !ok {
+		t.Fatal("expected hit after remount")
+	}
+	if got.Content[0].Text != "mcpfs" || string(got.Raw) != string(res.Raw) {
+		t.Errorf("unexpected cached result: %+v", got)
+	}
+
+	if st := c.Stats("@github/mcp"); st.Hits != 0 || st.Misses != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if st := c2.Stats("@github/mcp"); st.Hits != 1 || st.Misses != 0 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestExpiryAndErrors(t *testing.T) {
+	c := New(t.TempDir())
+
+	res := &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "x"}}}
+	c.Put("@a/b", "tool", nil, res, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := c.Get("@a/b", "tool", nil); ok {
+		t.Error("expected expired entry to miss")
+	}
+
+	failed := &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "boom"}}, IsError: true}
+	c.Put("@a/b", "tool", nil, failed, time.Minute)
+	if _, ok := c.Get("@a/b", "tool", nil); ok {
+		t.Error("expected error results not to be cached")
+	}
+}
+
+func TestConcurrentPut(t *testing.T) {
+	dir := t.TempDir()
+	c := New(dir)
+	res := &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "mcpfs"}}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- New(dir).Put("@github/mcp", "list_repos", nil, res, time.Minute)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+
+	if _, ok := c.Get("@github/mcp", "list_repos", nil); !ok {
+		t.Fatal("expected hit after concurrent puts")
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "*", "*.tmp"))
+	if len(leftovers) > 0 {
+		t.Errorf("expected no temp files left, got %v", leftovers)
+	}
+}
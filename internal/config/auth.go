@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 )
 
@@ -37,11 +39,29 @@ func LoadAuth(configDir, serverName string) (*Auth, error) {
 	return auth, nil
 }
 
+// SaveAuth writes auth to the store picked by AuthStore.
 func SaveAuth(configDir, serverName string, auth *Auth) error {
+	return SaveAuthTo(configDir, serverName, auth, AuthStore())
+}
+
+// SaveAuthTo writes auth under the config dir. with the keyring store the
+// values themselves go to the keyring and only references are written.
+func SaveAuthTo(configDir, serverName string, auth *Auth, store string) error {
 	if configDir == "" {
 		configDir = DefaultConfigDir()
 	}
 
+	switch store {
+	case AuthStoreFile, "":
+	case AuthStoreKeyring:
+		var err error
+		if auth, err = moveToKeyring(serverName, auth); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown auth store %q", store)
+	}
+
 	authDir := filepath.Join(configDir, "auth")
 	if err := os.MkdirAll(authDir, 0700); err != nil {
 		return fmt.Errorf("create auth dir: %w", err)
@@ -63,11 +83,135 @@ func SaveAuth(configDir, serverName string, auth *Auth) error {
 	return nil
 }
 
+// SaveAuthUpdate applies update (an empty value removes the key) to the
+// stored auth, writing each value back where it came from: a key held in the
+// keyring keeps its ${keyring:...} reference and the keyring gets the new
+// value. other keys go to the keyring too if the server already keeps
+// anything there, else to AuthStore.
+func SaveAuthUpdate(configDir, serverName string, stored *Auth, update map[string]string) error {
+	store := AuthStore()
+	for _, value := range stored.Data {
+		if keyringAccount(value) != "" {
+			store = AuthStoreKeyring
+			break
+		}
+	}
+
+	out := &Auth{Data: make(map[string]string, len(stored.Data))}
+	for key, value := range stored.Data {
+		out.Data[key] = value
+	}
+	for _, key := range sortedKeys(update) {
+		value := update[key]
+		if value == "" {
+			delete(out.Data, key)
+			continue
+		}
+		account := keyringAccount(stored.Data[key])
+		if account == "" && store == AuthStoreKeyring {
+			account = serverName + "/" + key
+		}
+		if account == "" {
+			out.Data[key] = value
+			continue
+		}
+		if err := keyringSet(account, value); err != nil {
+			return err
+		}
+		out.Data[key] = "${keyring:" + account + "}"
+	}
+	return SaveAuthTo(configDir, serverName, out, AuthStoreFile)
+}
+
+// UpdateAuth applies update to the server's stored auth with
+// SaveAuthUpdate, as a write to its .auth does.
+func UpdateAuth(configDir, serverName string, update map[string]string) error {
+	stored, err := LoadAuth(configDir, serverName)
+	if err != nil {
+		return err
+	}
+	return SaveAuthUpdate(configDir, serverName, stored, update)
+}
+
 func SaveToken(configDir, serverName, token string) error {
+	return SaveTokenTo(configDir, serverName, token, AuthStore())
+}
+
+func SaveTokenTo(configDir, serverName, token, store string) error {
 	auth := &Auth{
 		Data: map[string]string{
 			"token": token,
 		},
 	}
-	return SaveAuth(configDir, serverName, auth)
+	return SaveAuthTo(configDir, serverName, auth, store)
+}
+
+var authKeyPattern = regexp.MustCompile(`^\w+$`)
+
+// ParseAuthUpdate reads either a json object or key=value lines (blank
+// lines and # comments are skipped). an empty value means remove the key.
+func ParseAuthUpdate(data []byte) (map[string]string, error) {
+	text := strings.TrimSpace(string(data))
+	update := make(map[string]string)
+
+	if strings.HasPrefix(text, "{") {
+		var obj map[string]any
+		if err := json.Unmarshal([]byte(text), &obj); err != nil {
+			return nil, fmt.Errorf("parse auth json: %w", err)
+		}
+		for key, value := range obj {
+			switch v := value.(type) {
+			case string:
+				update[key] = v
+			case nil:
+				update[key] = ""
+			default:
+				raw, _ := json.Marshal(v)
+				update[key] = string(raw)
+			}
+		}
+	} else {
+		for i, line := range strings.Split(text, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return nil, fmt.Errorf("line %d: expected key=value", i+1)
+			}
+			update[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	for key := range update {
+		if !authKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid auth key %q: use letters, digits and _", key)
+		}
+	}
+	if len(update) == 0 {
+		return nil, fmt.Errorf("no auth values given")
+	}
+	return update, nil
+}
+
+// Apply merges an update into the stored values.
+func (a *Auth) Apply(update map[string]string) {
+	for key, value := range update {
+		if value == "" {
+			delete(a.Data, key)
+		} else {
+			a.Data[key] = value
+		}
+	}
+}
+
+// Keys lists the names of stored values, never the values.
+func (a *Auth) Keys() []string {
+	keys := make([]string, 0, len(a.Data))
+	for key := range a.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -4,9 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Transport string
@@ -23,11 +26,31 @@ type ServerConfig struct {
 	Env       map[string]string `json:"env,omitempty"`
 	URL       string            `json:"url,omitempty"`
 	Headers   map[string]string `json:"headers,omitempty"`
+	Roots     []string          `json:"roots,omitempty"`
+	Cache     map[string]string `json:"cache,omitempty"` // tool glob -> ttl, e.g. "list_*": "10m"
+
+	// ConfirmDestructive makes destructive tools wait for a write to .confirm
+	ConfirmDestructive bool `json:"confirmDestructive,omitempty"`
+
+	AllowTools []string                       `json:"allowTools,omitempty"` // tool globs, empty allows all
+	DenyTools  []string                       `json:"denyTools,omitempty"`  // tool globs, wins over allowTools
+	ArgRules   map[string]map[string][]string `json:"argRules,omitempty"`   // tool glob -> argument -> allowed value globs
+
+	// AuthErrors are regexes marking an error, or a tool's isError result, as
+	// expired credentials, on top of http 401. RefreshCommand renews them
+	// when oauth can't: its output, if any, is stored like a write to .auth
+	// (a single line that isn't key=value is the token).
+	AuthErrors     []string `json:"authErrors,omitempty"`
+	RefreshCommand string   `json:"refreshCommand,omitempty"`
 }
 
 type Config struct {
 	Servers map[string]*ServerConfig `json:"servers"`
 	dir     string
+	mu      sync.RWMutex // guards Servers once a mount shares the config
+
+	project  *Project                 // merged over Servers, see SetProject
+	shadowed map[string]*ServerConfig // saved servers hidden by the project
 }
 
 func DefaultConfigDir() string {
@@ -54,23 +77,33 @@ func Load(configDir string) (*Config, error) {
 		return nil, fmt.Errorf("read servers.json: %w", err)
 	}
 
-	if err := json.Unmarshal(data, &cfg.Servers); err != nil {
-		return nil, fmt.Errorf("parse servers.json: %w", err)
+	if cfg.Servers, err = ParseServers(data); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
 func (c *Config) Save() error {
+	return c.SaveServers(c.Saved())
+}
+
+// SaveServers writes servers as servers.json without touching the config in
+// memory, so a mount can apply an edit only once it is on disk.
+func (c *Config) SaveServers(servers map[string]*ServerConfig) error {
 	if err := os.MkdirAll(c.dir, 0755); err != nil {
 		return fmt.Errorf("create config dir: %w", err)
 	}
 
 	serversPath := filepath.Join(c.dir, "servers.json")
-	data, err := json.MarshalIndent(c.Servers, "", "  ")
+	data, err := json.MarshalIndent(servers, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal servers: %w", err)
 	}
+	// never write what Load would refuse to read back
+	if _, err := ParseServers(data); err != nil {
+		return err
+	}
 
 	if err := os.WriteFile(serversPath, data, 0644); err != nil {
 		return fmt.Errorf("write servers.json: %w", err)
@@ -80,32 +113,129 @@ func (c *Config) Save() error {
 }
 
 func (c *Config) AddStdioServer(name, command string, args []string, env map[string]string) {
-	c.Servers[name] = &ServerConfig{
+	c.SetServer(name, &ServerConfig{
 		Transport: TransportStdio,
 		Command:   command,
 		Args:      args,
 		Env:       env,
-	}
+	})
 }
 
 func (c *Config) AddHTTPServer(name, url string, headers map[string]string) {
-	c.Servers[name] = &ServerConfig{
+	c.SetServer(name, &ServerConfig{
 		Transport: TransportHTTP,
 		URL:       url,
 		Headers:   headers,
+	})
+}
+
+// SetServer adds or replaces a server. server configs are treated as
+// immutable once shared, so edits go through a copy and this.
+func (c *Config) SetServer(name string, srv *ServerConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forgetProject(name)
+	c.Servers[name] = srv
+}
+
+// RemoveServer deletes a server and reports whether it existed.
+func (c *Config) RemoveServer(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.forgetProject(name)
+	_, ok := c.Servers[name]
+	delete(c.Servers, name)
+	return ok
+}
+
+// forgetProject turns a project server into a saved one. c.mu must be held.
+func (c *Config) forgetProject(name string) {
+	if c.project != nil {
+		delete(c.project.Servers, name)
 	}
+	delete(c.shadowed, name)
 }
 
 func (c *Config) GetServer(name string) (*ServerConfig, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	srv, ok := c.Servers[name]
 	return srv, ok
 }
 
+// Names returns the configured server names, sorted.
+func (c *Config) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.Servers))
+	for name := range c.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Snapshot returns a copy of the server map that is safe to range over
+// while the config is being reloaded.
+func (c *Config) Snapshot() map[string]*ServerConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	servers := make(map[string]*ServerConfig, len(c.Servers))
+	for name, srv := range c.Servers {
+		servers[name] = srv
+	}
+	return servers
+}
+
+// Saved returns the servers Save writes: Snapshot without the project's
+// servers, and with the ones they hide.
+func (c *Config) Saved() map[string]*ServerConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	servers := make(map[string]*ServerConfig, len(c.Servers))
+	for name, srv := range c.Servers {
+		if !c.fromProject(name) {
+			servers[name] = srv
+		} else if hidden, ok := c.shadowed[name]; ok {
+			servers[name] = hidden
+		}
+	}
+	return servers
+}
+
 func (c *Config) Dir() string {
 	return c.dir
 }
 
-var authVarPattern = regexp.MustCompile(`\$\{auth\.(\w+)\}`)
+// Resolve expands references in env and headers, failing if any secret
+// can't be fetched. ResolveEnv and ResolveHeaders keep unresolved
+// references as they are.
+func (s *ServerConfig) Resolve(auth *Auth) (env, headers map[string]string, err error) {
+	env, headers, _, err = s.ResolveSecrets(auth)
+	return env, headers, err
+}
+
+// ResolveSecrets is Resolve that also returns every value the references
+// resolved to, for redaction.
+func (s *ServerConfig) ResolveSecrets(auth *Auth) (env, headers map[string]string, secrets []string, err error) {
+	found := func(v string) { secrets = append(secrets, v) }
+	env = make(map[string]string)
+	for k, v := range s.Env {
+		if env[k], err = expand(v, auth, found); err != nil {
+			return nil, nil, nil, fmt.Errorf("env %s: %w", k, err)
+		}
+	}
+	headers = make(map[string]string)
+	for k, v := range s.Headers {
+		if headers[k], err = expand(v, auth, found); err != nil {
+			return nil, nil, nil, fmt.Errorf("header %s: %w", k, err)
+		}
+	}
+	return env, headers, secrets, nil
+}
 
 func (s *ServerConfig) ResolveEnv(auth *Auth) map[string]string {
 	resolved := make(map[string]string)
@@ -123,21 +253,30 @@ func (s *ServerConfig) ResolveHeaders(auth *Auth) map[string]string {
 	return resolved
 }
 
-func resolveAuthVars(s string, auth *Auth) string {
-	if auth == nil {
-		return s
+// CacheTTL returns how long results of tool may be reused. an exact name
+// wins over glob patterns; tools without a match aren't cached.
+func (s *ServerConfig) CacheTTL(tool string) time.Duration {
+	if ttl, ok := s.Cache[tool]; ok {
+		d, _ := time.ParseDuration(ttl)
+		return d
 	}
-	return authVarPattern.ReplaceAllStringFunc(s, func(match string) string {
-		parts := authVarPattern.FindStringSubmatch(match)
-		if len(parts) < 2 {
-			return match
-		}
-		key := parts[1]
-		if val, ok := auth.Data[key]; ok {
-			return val
+
+	var best string
+	for pattern := range s.Cache {
+		if ok, _ := path.Match(pattern, tool); ok && len(pattern) > len(best) {
+			best = pattern
 		}
-		return match
-	})
+	}
+	if best == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(s.Cache[best])
+	return d
+}
+
+func resolveAuthVars(s string, auth *Auth) string {
+	out, _ := expandVars(s, auth)
+	return out
 }
 
 func ParseServerName(name string) (scope, server string) {
@@ -149,6 +288,36 @@ func ParseServerName(name string) (scope, server string) {
 	return "", name
 }
 
+// ParseServers reads a servers.json document and rejects servers that could
+// never start.
+func ParseServers(data []byte) (map[string]*ServerConfig, error) {
+	servers := make(map[string]*ServerConfig)
+	if err := json.Unmarshal(data, &servers); err != nil {
+		return nil, fmt.Errorf("parse servers.json: %w", err)
+	}
+	if servers == nil {
+		servers = make(map[string]*ServerConfig)
+	}
+
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, p := range checkNames(names) {
+		if p.Severity == SeverityFatal {
+			return nil, fmt.Errorf("%s: %s", p.Server, p.Message)
+		}
+	}
+	for _, name := range names {
+		if problems := checkServer(servers[name]); len(problems) > 0 {
+			return nil, fmt.Errorf("%s: %s", name, problems[0])
+		}
+	}
+	return servers, nil
+}
+
 func MarshalServers(servers map[string]*ServerConfig) ([]byte, error) {
 	return json.MarshalIndent(servers, "", "  ")
 }
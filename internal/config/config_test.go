@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoadEmpty(t *testing.T) {
@@ -153,3 +154,92 @@ func TestAuthNotExists(t *testing.T) {
 		t.Errorf("expected empty auth data, got %v", auth.Data)
 	}
 }
+
+func TestCacheTTL(t *testing.T) {
+	srv := &ServerConfig{
+		Cache: map[string]string{
+			"list_repos": "10m",
+			"list_*":     "1m",
+			"*":          "5s",
+			"broken":     "soon",
+		},
+	}
+
+	tests := []struct {
+		tool string
+		want time.Duration
+	}{
+		{"list_repos", 10 * time.Minute},
+		{"list_issues", time.Minute},
+		{"search", 5 * time.Second},
+		{"broken", 0},
+	}
+	for _, tt := range tests {
+		if got := srv.CacheTTL(tt.tool); got != tt.want {
+			t.Errorf("CacheTTL(%q) = %v, want %v", tt.tool, got, tt.want)
+		}
+	}
+
+	if ttl := (&ServerConfig{}).CacheTTL("anything"); ttl != 0 {
+		t.Errorf("expected no caching without config, got %v", ttl)
+	}
+}
+
+func TestParseServers(t *testing.T) {
+	servers, err := ParseServers([]byte(`{
+		"@a/stdio": {"transport": "stdio", "command": "npx"},
+		"@a/http": {"transport": "http", "url": "https://example.com/mcp"}
+	}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(servers) != 2 {
+		t.Errorf("expected 2 servers, got %d", len(servers))
+	}
+
+	bad := []string{
+		`{"@a/b": `,
+		`{"@a/b": {"transport": "stdio"}}`,
+		`{"@a/b": {"transport": "http"}}`,
+		`{"@a/b": {"transport": "carrier-pigeon", "command": "x"}}`,
+		`{"@a/b": null}`,
+	}
+	for _, data := range bad {
+		if _, err := ParseServers([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
+
+func TestParseAuthUpdate(t *testing.T) {
+	update, err := ParseAuthUpdate([]byte("# rotated\ntoken = ghp_new\n\nold=\n"))
+	if err != nil {
+		t.Fatalf("parse lines: %v", err)
+	}
+	if update["token"] != "ghp_new" || update["old"] != "" || len(update) != 2 {
+		t.Errorf("unexpected update: %v", update)
+	}
+
+	update, err = ParseAuthUpdate([]byte(`{"token": "abc", "port": 5432, "gone": null}`))
+	if err != nil {
+		t.Fatalf("parse json: %v", err)
+	}
+	if update["token"] != "abc" || update["port"] != "5432" || update["gone"] != "" {
+		t.Errorf("unexpected update: %v", update)
+	}
+
+	for _, bad := range []string{"", "just a token", "bad key=x", `{"token": `} {
+		if _, err := ParseAuthUpdate([]byte(bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+
+	auth := &Auth{Data: map[string]string{"token": "old", "old": "x", "keep": "y"}}
+	auth.Apply(map[string]string{"token": "new", "old": ""})
+	if keys := auth.Keys(); len(keys) != 2 || keys[0] != "keep" || keys[1] != "token" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+	if auth.Data["token"] != "new" {
+		t.Errorf("expected token updated, got %q", auth.Data["token"])
+	}
+}
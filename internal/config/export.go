@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TargetJSON is the plain mcpServers shape, for clients without their own
+// export target.
+const TargetJSON = "json"
+
+// ExportOptions pick how secrets reach the exported client.
+type ExportOptions struct {
+	// ResolveAuth writes resolved secrets into the output instead of
+	// ${auth.x} references, which other clients can't read.
+	ResolveAuth bool
+	// Proxy, when set, replaces every entry with this command line plus the
+	// server name, i.e. "mcpfs proxy"; nothing else is exported.
+	Proxy []string
+}
+
+var ourEnvPattern = regexp.MustCompile(`\$\{env\.([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExportedName is the reverse of ImportedName: @github/mcp becomes github,
+// @corp/tools becomes corp-tools.
+func ExportedName(name string) string {
+	scope, server := ParseServerName(name)
+	if scope == "" {
+		return server
+	}
+	scope = strings.TrimPrefix(scope, "@")
+	if server == "mcp" {
+		return scope
+	}
+	return scope + "-" + strings.ReplaceAll(server, "/", "-")
+}
+
+// ExportServers renders the config for target (claude-desktop, claude-code,
+// cursor, vscode or json). notes list what the target won't be able to use
+// as exported.
+func (c *Config) ExportServers(target string, opts ExportOptions) (data []byte, notes []string, err error) {
+	switch target {
+	case SourceClaudeDesktop, SourceClaudeCode, SourceCursor, SourceVSCode, TargetJSON:
+	default:
+		return nil, nil, fmt.Errorf("unknown export target %q", target)
+	}
+
+	servers := make(map[string]*ExternalServer)
+	for _, name := range c.Names() {
+		srv, _ := c.GetServer(name)
+		ext, note, err := c.exportServer(name, srv, target, opts)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if note != "" {
+			notes = append(notes, name+": "+note)
+		}
+
+		key := ExportedName(name)
+		if _, taken := servers[key]; taken {
+			key = strings.TrimPrefix(strings.ReplaceAll(name, "/", "-"), "@")
+		}
+		servers[key] = ext
+	}
+
+	doc := map[string]any{"mcpServers": servers}
+	if target == SourceVSCode {
+		doc = map[string]any{"servers": servers}
+	}
+	data, err = json.MarshalIndent(doc, "", "  ")
+	return data, notes, err
+}
+
+func (c *Config) exportServer(name string, srv *ServerConfig, target string, opts ExportOptions) (*ExternalServer, string, error) {
+	if len(opts.Proxy) > 0 {
+		ext := &ExternalServer{
+			Command: opts.Proxy[0],
+			Args:    append(append([]string{}, opts.Proxy[1:]...), name),
+		}
+		if target == SourceVSCode {
+			ext.Type = "stdio"
+		}
+		return ext, "", nil
+	}
+
+	env, headers := srv.Env, srv.Headers
+	if opts.ResolveAuth {
+		auth, err := LoadAuth(c.dir, name)
+		if err != nil {
+			return nil, "", err
+		}
+		if env, headers, err = srv.Resolve(auth); err != nil {
+			return nil, "", err
+		}
+	}
+
+	ext := &ExternalServer{}
+	var note string
+	switch srv.Transport {
+	case TransportStdio:
+		if target == SourceVSCode {
+			ext.Type = "stdio"
+		}
+		ext.Command = exportEnvRefs(srv.Command, target)
+		for _, arg := range srv.Args {
+			ext.Args = append(ext.Args, exportEnvRefs(arg, target))
+		}
+		ext.Env = exportValues(env, target)
+	case TransportHTTP:
+		ext.Type = "http"
+		ext.URL = exportEnvRefs(srv.URL, target)
+		ext.Headers = exportValues(headers, target)
+		if target == SourceClaudeDesktop {
+			note = "claude desktop only runs stdio servers from its config; use --proxy"
+		}
+	}
+
+	if note == "" && hasRefs(ext) {
+		note = "still references secrets the client can't resolve; use --resolve-auth or --proxy"
+	}
+	return ext, note, nil
+}
+
+// exportEnvRefs rewrites ${env.X} into the target's own syntax where it has
+// one.
+func exportEnvRefs(s, target string) string {
+	switch target {
+	case SourceVSCode:
+		return ourEnvPattern.ReplaceAllString(s, "$${env:$1}")
+	case SourceClaudeCode:
+		return ourEnvPattern.ReplaceAllString(s, "$${$1}")
+	}
+	return s
+}
+
+func exportValues(m map[string]string, target string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = exportEnvRefs(v, target)
+	}
+	return out
+}
+
+func hasRefs(ext *ExternalServer) bool {
+	values := append([]string{ext.Command, ext.URL}, ext.Args...)
+	for _, v := range ext.Env {
+		values = append(values, v)
+	}
+	for _, v := range ext.Headers {
+		values = append(values, v)
+	}
+	for _, v := range values {
+		if secretVarPattern.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
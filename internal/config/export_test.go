@@ -0,0 +1,78 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExportServers(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := Load(dir)
+	cfg.AddStdioServer("@github/mcp", "npx", []string{"-y", "server-github"}, map[string]string{
+		"GITHUB_TOKEN": "${auth.token}",
+		"HOME_DIR":     "${env.HOME}",
+	})
+	cfg.AddHTTPServer("@corp/tools", "https://corp.example.com/mcp", map[string]string{
+		"Authorization": "Bearer ${auth.token}",
+	})
+	SaveTokenTo(dir, "@github/mcp", "ghp_secret", AuthStoreFile)
+	SaveTokenTo(dir, "@corp/tools", "corp_secret", AuthStoreFile)
+
+	data, notes, err := cfg.ExportServers(SourceVSCode, ExportOptions{})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	var vscode struct {
+		Servers map[string]*ExternalServer `json:"servers"`
+	}
+	json.Unmarshal(data, &vscode)
+	gh := vscode.Servers["github"]
+	if gh == nil || gh.Type != "stdio" || gh.Env["HOME_DIR"] != "${env:HOME}" || gh.Env["GITHUB_TOKEN"] != "${auth.token}" {
+		t.Errorf("unexpected github export: %s", data)
+	}
+	if corp := vscode.Servers["corp-tools"]; corp == nil || corp.Type != "http" || corp.URL != "https://corp.example.com/mcp" {
+		t.Errorf("unexpected corp export: %s", data)
+	}
+	if len(notes) != 2 {
+		t.Errorf("expected notes about unresolved auth, got %v", notes)
+	}
+
+	data, notes, err = cfg.ExportServers(SourceCursor, ExportOptions{ResolveAuth: true})
+	if err != nil {
+		t.Fatalf("export resolved: %v", err)
+	}
+	var cursor struct {
+		MCPServers map[string]*ExternalServer `json:"mcpServers"`
+	}
+	json.Unmarshal(data, &cursor)
+	if cursor.MCPServers["github"].Env["GITHUB_TOKEN"] != "ghp_secret" || cursor.MCPServers["corp-tools"].Headers["Authorization"] != "Bearer corp_secret" {
+		t.Errorf("expected resolved secrets, got %s", data)
+	}
+	if len(notes) != 0 {
+		t.Errorf("expected no notes, got %v", notes)
+	}
+
+	data, _, err = cfg.ExportServers(SourceClaudeDesktop, ExportOptions{Proxy: []string{"/usr/local/bin/mcpfs", "proxy"}})
+	if err != nil {
+		t.Fatalf("export proxy: %v", err)
+	}
+	var desktop struct {
+		MCPServers map[string]*ExternalServer `json:"mcpServers"`
+	}
+	json.Unmarshal(data, &desktop)
+	corp := desktop.MCPServers["corp-tools"]
+	if corp.Command != "/usr/local/bin/mcpfs" || len(corp.Args) != 2 || corp.Args[0] != "proxy" || corp.Args[1] != "@corp/tools" || corp.Env != nil {
+		t.Errorf("unexpected proxy export: %+v", corp)
+	}
+
+	// export output is valid import input; @github/mcp survives the round trip
+	external, _ := ParseExternalServers(data)
+	imported := ImportServers(external)
+	if len(imported) != 2 || imported[0].Name != "@corp-tools/mcp" || imported[1].Name != "@github/mcp" {
+		t.Errorf("unexpected round trip: %+v", imported)
+	}
+
+	if _, _, err := cfg.ExportServers("emacs", ExportOptions{}); err == nil {
+		t.Error("expected unknown target error")
+	}
+}
@@ -0,0 +1,336 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// import sources understood by ImportPaths, besides a plain file path
+const (
+	SourceClaudeDesktop = "claude-desktop"
+	SourceClaudeCode    = "claude-code"
+	SourceCursor        = "cursor"
+	SourceVSCode        = "vscode"
+)
+
+// ExternalServer is one entry of the mcpServers shape shared by claude,
+// cursor and vs code.
+type ExternalServer struct {
+	Type    string            `json:"type,omitempty"`
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Cwd     string            `json:"cwd,omitempty"`
+}
+
+// Imported is a server converted to our config, with the literal secrets
+// that were lifted out of it into Auth. a server that couldn't be converted
+// has Skipped set to why, and no Server.
+type Imported struct {
+	Name    string
+	Server  *ServerConfig
+	Auth    map[string]string
+	Notes   []string // things that didn't carry over
+	Skipped string
+}
+
+// env and header names whose literal values belong in the auth store
+var secretNamePattern = regexp.MustCompile(`(?i)token|secret|passw|api_?key|access_?key|private_?key|credential|auth|(^|_)pat($|_)`)
+
+// ${VAR} (claude code) and ${env:VAR} (vs code) become ${env.VAR}
+var foreignEnvPattern = regexp.MustCompile(`\$\{(?:env:)?([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+var (
+	unsafeNameChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+	nonWordChars    = regexp.MustCompile(`\W+`)
+)
+
+// ImportPaths returns the files a source is read from, in order. a source
+// that isn't a known client name is taken as a path.
+func ImportPaths(source string) ([]string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	switch source {
+	case SourceClaudeDesktop:
+		return []string{filepath.Join(appConfigDir(home), "Claude", "claude_desktop_config.json")}, nil
+	case SourceClaudeCode:
+		return []string{filepath.Join(home, ".claude.json"), ".mcp.json"}, nil
+	case SourceCursor:
+		return []string{filepath.Join(home, ".cursor", "mcp.json"), filepath.Join(".cursor", "mcp.json")}, nil
+	case SourceVSCode:
+		user := filepath.Join(appConfigDir(home), "Code", "User")
+		return []string{
+			filepath.Join(user, "settings.json"),
+			filepath.Join(user, "mcp.json"),
+			filepath.Join(".vscode", "mcp.json"),
+		}, nil
+	}
+
+	if strings.HasPrefix(source, "~/") {
+		source = filepath.Join(home, source[2:])
+	}
+	return []string{source}, nil
+}
+
+func appConfigDir(home string) string {
+	switch runtime.GOOS {
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support")
+	case "windows":
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return appData
+		}
+		return filepath.Join(home, "AppData", "Roaming")
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return xdg
+	}
+	return filepath.Join(home, ".config")
+}
+
+// ParseExternalServers finds the server map in a client config: top level
+// mcpServers (claude, cursor), servers (vs code mcp.json) or mcp.servers
+// (vs code settings.json). comments and trailing commas are allowed.
+func ParseExternalServers(data []byte) (map[string]*ExternalServer, error) {
+	var doc struct {
+		MCPServers map[string]*ExternalServer `json:"mcpServers"`
+		Servers    map[string]*ExternalServer `json:"servers"`
+		MCP        struct {
+			Servers map[string]*ExternalServer `json:"servers"`
+		} `json:"mcp"`
+		MCPDotServers map[string]*ExternalServer `json:"mcp.servers"`
+	}
+	if err := json.Unmarshal(stripJSONC(data), &doc); err != nil {
+		return nil, fmt.Errorf("parse mcp config: %w", err)
+	}
+
+	servers := make(map[string]*ExternalServer)
+	for _, m := range []map[string]*ExternalServer{doc.MCPDotServers, doc.MCP.Servers, doc.Servers, doc.MCPServers} {
+		for name, srv := range m {
+			if srv != nil {
+				servers[name] = srv
+			}
+		}
+	}
+	return servers, nil
+}
+
+// ImportServers converts external entries, sorted by name. names become
+// "@<name>/mcp" unless they are already scoped. an entry that can't be
+// converted is returned with Skipped set, so one bad entry doesn't stop the
+// rest.
+func ImportServers(servers map[string]*ExternalServer) []Imported {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []Imported
+	for _, name := range names {
+		imp, err := importServer(name, servers[name])
+		if err != nil {
+			imp = Imported{Name: imp.Name, Skipped: err.Error()}
+		}
+		out = append(out, imp)
+	}
+	return out
+}
+
+// ImportedName maps a client's server name to a scoped one.
+func ImportedName(name string) string {
+	if scope, server := ParseServerName(name); scope != "" {
+		return scope + "/" + server
+	}
+	name = unsafeNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-")
+	return "@" + name + "/mcp"
+}
+
+func importServer(name string, ext *ExternalServer) (Imported, error) {
+	imp := Imported{
+		Name:   ImportedName(name),
+		Server: &ServerConfig{},
+		Auth:   make(map[string]string),
+	}
+	srv := imp.Server
+
+	switch strings.ToLower(ext.Type) {
+	case "", "stdio":
+		if ext.Command == "" {
+			if ext.URL == "" {
+				return imp, fmt.Errorf("needs a command or url")
+			}
+			srv.Transport = TransportHTTP
+		} else {
+			srv.Transport = TransportStdio
+		}
+	case "http", "streamable-http", "streamablehttp":
+		srv.Transport = TransportHTTP
+	case "sse":
+		// the old http+sse transport; many of these servers also answer
+		// streamable http, often at /mcp instead of /sse
+		srv.Transport = TransportHTTP
+		imp.Notes = append(imp.Notes, "sse transport is not supported; imported as streamable http, which works only if the server speaks it at this url")
+	default:
+		return imp, fmt.Errorf("unknown type %q", ext.Type)
+	}
+
+	if srv.Transport == TransportStdio {
+		srv.Command = translateEnvRefs(ext.Command)
+		for _, arg := range ext.Args {
+			srv.Args = append(srv.Args, translateEnvRefs(arg))
+		}
+		if len(ext.Env) > 0 {
+			srv.Env = make(map[string]string)
+			for _, key := range sortedKeys(ext.Env) {
+				srv.Env[key] = imp.liftSecret(key, ext.Env[key])
+			}
+		}
+		if ext.Cwd != "" {
+			imp.Notes = append(imp.Notes, "cwd is not supported and was dropped")
+		}
+	} else {
+		if ext.URL == "" {
+			return imp, fmt.Errorf("%s server needs a url", ext.Type)
+		}
+		// references are only expanded in env and headers, and a url
+		// that isn't one can't be saved
+		if foreignEnvPattern.MatchString(ext.URL) {
+			return imp, fmt.Errorf("url %q takes an environment variable, which mcpfs doesn't expand in urls; add it with mcpfs add", ext.URL)
+		}
+		srv.URL = ext.URL
+		if len(ext.Headers) > 0 {
+			srv.Headers = make(map[string]string)
+			for _, key := range sortedKeys(ext.Headers) {
+				srv.Headers[key] = imp.liftHeader(key, ext.Headers[key])
+			}
+		}
+	}
+	return imp, nil
+}
+
+// liftSecret replaces a literal secret with an ${auth.x} reference and
+// records the value.
+func (imp *Imported) liftSecret(key, value string) string {
+	value = translateEnvRefs(value)
+	if value == "" || strings.Contains(value, "${") || !secretNamePattern.MatchString(key) {
+		return value
+	}
+	authKey := imp.authKey(strings.ToLower(key))
+	imp.Auth[authKey] = value
+	return "${auth." + authKey + "}"
+}
+
+func (imp *Imported) liftHeader(key, value string) string {
+	value = translateEnvRefs(value)
+	if strings.EqualFold(key, "Authorization") && !strings.Contains(value, "${") {
+		if scheme, token, ok := strings.Cut(value, " "); ok && strings.TrimSpace(token) != "" {
+			authKey := imp.authKey("token")
+			imp.Auth[authKey] = strings.TrimSpace(token)
+			return scheme + " ${auth." + authKey + "}"
+		}
+	}
+	return imp.liftSecret(strings.ReplaceAll(key, "-", "_"), value)
+}
+
+// authKey avoids clobbering a value lifted earlier from another field.
+func (imp *Imported) authKey(key string) string {
+	key = nonWordChars.ReplaceAllString(key, "_")
+	candidate := key
+	for i := 2; ; i++ {
+		if _, taken := imp.Auth[candidate]; !taken {
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s_%d", key, i)
+	}
+}
+
+func translateEnvRefs(s string) string {
+	return foreignEnvPattern.ReplaceAllString(s, "$${env.$1}")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stripJSONC drops // and /* */ comments, then trailing commas, leaving
+// strings alone.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	scanJSON(data, func(i int, c byte) int {
+		switch {
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			return i
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			return i + 2
+		}
+		out = append(out, c)
+		return i + 1
+	}, func(b []byte) { out = append(out, b...) })
+
+	data, out = out, make([]byte, 0, len(out))
+	scanJSON(data, func(i int, c byte) int {
+		if c == ',' {
+			j := i + 1
+			for j < len(data) && strings.IndexByte(" \t\r\n", data[j]) >= 0 {
+				j++
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				return i + 1
+			}
+		}
+		out = append(out, c)
+		return i + 1
+	}, func(b []byte) { out = append(out, b...) })
+	return out
+}
+
+// scanJSON hands string literals, quotes included, to str and everything
+// else byte by byte to other, which returns where to continue.
+func scanJSON(data []byte, other func(i int, c byte) int, str func([]byte)) {
+	for i := 0; i < len(data); {
+		if data[i] != '"' {
+			i = other(i, data[i])
+			continue
+		}
+		j := i + 1
+		for j < len(data) && data[j] != '"' {
+			if data[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		if j < len(data) {
+			j++
+		}
+		if j > len(data) {
+			j = len(data)
+		}
+		str(data[i:j])
+		i = j
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExternalServers(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"claude", `{"mcpServers": {"github": {"command": "npx"}}}`, []string{"github"}},
+		{"vscode mcp.json", `{
+			// workspace servers
+			"servers": {"linear": {"type": "http", "url": "https://mcp.linear.app/mcp"},},
+		}`, []string{"linear"}},
+		{"vscode settings", `{"editor.tabSize": 2, /* keep "this" */ "mcp": {"servers": {"a": {"command": "a"}}}}`, []string{"a"}},
+		{"comment-like string", `{"mcpServers": {"b": {"command": "http://x//y", "args": ["/*", "*/"]}}}`, []string{"b"}},
+	}
+	for _, tt := range tests {
+		servers, err := ParseExternalServers([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(servers) != len(tt.want) {
+			t.Errorf("%s: got %v", tt.name, servers)
+			continue
+		}
+		for _, name := range tt.want {
+			if servers[name] == nil {
+				t.Errorf("%s: missing %s", tt.name, name)
+			}
+		}
+	}
+
+	servers, _ := ParseExternalServers([]byte(`{"mcpServers": {"b": {"command": "http://x//y", "args": ["/*", "*/"]}}}`))
+	if b := servers["b"]; b.Command != "http://x//y" || len(b.Args) != 2 || b.Args[0] != "/*" {
+		t.Errorf("expected strings untouched, got %+v", b)
+	}
+}
+
+func TestImportServers(t *testing.T) {
+	imported := ImportServers(map[string]*ExternalServer{
+		"GitHub Server": {
+			Command: "npx",
+			Args:    []string{"-y", "@modelcontextprotocol/server-github"},
+			Env: map[string]string{
+				"GITHUB_PERSONAL_ACCESS_TOKEN": "ghp_literal",
+				"GITHUB_HOST":                  "github.com",
+				"OTHER_TOKEN":                  "${OTHER_TOKEN}",
+			},
+		},
+		"linear": {
+			Type:    "http",
+			URL:     "https://mcp.linear.app/mcp",
+			Headers: map[string]string{"Authorization": "Bearer lin_literal", "X-Api-Key": "k123"},
+		},
+		"@corp/tools": {Type: "sse", URL: "https://corp.example.com/sse"},
+		"bad":         {},
+		"weird":       {Type: "websocket", URL: "wss://example.com"},
+		"envurl":      {Type: "http", URL: "https://${MCP_HOST}/mcp"},
+	})
+	if len(imported) != 6 {
+		t.Fatalf("expected 6 servers, got %d", len(imported))
+	}
+
+	corp, github, bad, envURL, linear, weird := imported[0], imported[1], imported[2], imported[3], imported[4], imported[5]
+	if envURL.Server != nil || !strings.Contains(envURL.Skipped, "environment variable") {
+		t.Errorf("expected a url with an env reference skipped, got %+v", envURL)
+	}
+	if bad.Server != nil || !strings.Contains(bad.Skipped, "command or url") {
+		t.Errorf("expected a server without command or url skipped, got %+v", bad)
+	}
+	if weird.Name != "@weird/mcp" || weird.Server != nil || !strings.Contains(weird.Skipped, "websocket") {
+		t.Errorf("expected an unknown type skipped, got %+v", weird)
+	}
+	if corp.Name != "@corp/tools" || corp.Server.Transport != TransportHTTP {
+		t.Errorf("unexpected corp import: %+v", corp)
+	}
+	if len(corp.Notes) != 1 || !strings.Contains(corp.Notes[0], "sse") {
+		t.Errorf("expected a note about the sse transport, got %v", corp.Notes)
+	}
+	if len(linear.Notes) != 0 {
+		t.Errorf("expected no notes for streamable http, got %v", linear.Notes)
+	}
+
+	if github.Name != "@github-server/mcp" || github.Server.Transport != TransportStdio {
+		t.Errorf("unexpected github import: %+v", github)
+	}
+	env := github.Server.Env
+	if env["GITHUB_PERSONAL_ACCESS_TOKEN"] != "${auth.github_personal_access_token}" || env["GITHUB_HOST"] != "github.com" || env["OTHER_TOKEN"] != "${env.OTHER_TOKEN}" {
+		t.Errorf("unexpected env: %v", env)
+	}
+	if len(github.Auth) != 1 || github.Auth["github_personal_access_token"] != "ghp_literal" {
+		t.Errorf("unexpected auth: %v", github.Auth)
+	}
+
+	headers := linear.Server.Headers
+	if headers["Authorization"] != "Bearer ${auth.token}" || headers["X-Api-Key"] != "${auth.x_api_key}" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+	if linear.Auth["token"] != "lin_literal" || linear.Auth["x_api_key"] != "k123" {
+		t.Errorf("unexpected auth: %v", linear.Auth)
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ToolAllowed checks a tool against the server's allowTools/denyTools
+// lists. the error explains which rule refused it.
+func (s *ServerConfig) ToolAllowed(tool string) error {
+	if pattern, ok := matchAny(s.DenyTools, tool); ok {
+		return fmt.Errorf("tool %s is denied by denyTools pattern %q", tool, pattern)
+	}
+	if len(s.AllowTools) > 0 {
+		if _, ok := matchAny(s.AllowTools, tool); !ok {
+			return fmt.Errorf("tool %s is not in allowTools", tool)
+		}
+	}
+	return nil
+}
+
+// CheckCall applies ToolAllowed and then every argRules entry whose tool
+// glob matches. string values are matched as is, anything else as json;
+// each element of an array must match. arguments that aren't passed are
+// left to the server.
+func (s *ServerConfig) CheckCall(tool string, args map[string]any) error {
+	if err := s.ToolAllowed(tool); err != nil {
+		return err
+	}
+
+	var toolPatterns []string
+	for pattern := range s.ArgRules {
+		if ok, _ := path.Match(pattern, tool); ok {
+			toolPatterns = append(toolPatterns, pattern)
+		}
+	}
+	sort.Strings(toolPatterns)
+
+	for _, toolPattern := range toolPatterns {
+		rules := s.ArgRules[toolPattern]
+		names := make([]string, 0, len(rules))
+		for name := range rules {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			value, ok := args[name]
+			if !ok {
+				continue
+			}
+			values := []any{value}
+			if arr, ok := value.([]any); ok {
+				values = arr
+			}
+			for _, v := range values {
+				text := argText(v)
+				if _, ok := matchAny(rules[name], text); !ok {
+					return fmt.Errorf("argument %s=%s is not allowed for %s (allowed: %s)",
+						name, text, tool, strings.Join(rules[name], ", "))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, s string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func argText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
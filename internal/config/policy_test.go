@@ -0,0 +1,66 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolAllowed(t *testing.T) {
+	srv := &ServerConfig{
+		AllowTools: []string{"list_*", "get_*", "delete_branch"},
+		DenyTools:  []string{"delete_*", "get_secret"},
+	}
+
+	tests := []struct {
+		tool string
+		ok   bool
+	}{
+		{"list_repos", true},
+		{"get_issue", true},
+		{"get_secret", false},
+		{"delete_branch", false}, // deny wins
+		{"create_issue", false},
+	}
+	for _, tt := range tests {
+		if err := srv.ToolAllowed(tt.tool); (err == nil) != tt.ok {
+			t.Errorf("ToolAllowed(%q) = %v, want ok=%v", tt.tool, err, tt.ok)
+		}
+	}
+
+	if err := (&ServerConfig{}).ToolAllowed("anything"); err != nil {
+		t.Errorf("expected everything allowed without policy, got %v", err)
+	}
+
+	err := srv.ToolAllowed("delete_repo")
+	if err == nil || !strings.Contains(err.Error(), `"delete_*"`) {
+		t.Errorf("expected error naming the pattern, got %v", err)
+	}
+}
+
+func TestCheckCall(t *testing.T) {
+	srv := &ServerConfig{
+		DenyTools: []string{"admin_*"},
+		ArgRules: map[string]map[string][]string{
+			"*":          {"repo": {"ourorg/*"}},
+			"set_labels": {"labels": {"bug", "docs"}},
+		},
+	}
+
+	tests := []struct {
+		tool string
+		args map[string]any
+		ok   bool
+	}{
+		{"get_issue", map[string]any{"repo": "ourorg/mcpfs", "number": 1.0}, true},
+		{"get_issue", map[string]any{"repo": "other/mcpfs"}, false},
+		{"get_issue", map[string]any{"number": 1.0}, true},
+		{"set_labels", map[string]any{"repo": "ourorg/x", "labels": []any{"bug", "docs"}}, true},
+		{"set_labels", map[string]any{"repo": "ourorg/x", "labels": []any{"bug", "wontfix"}}, false},
+		{"admin_delete", map[string]any{"repo": "ourorg/x"}, false},
+	}
+	for _, tt := range tests {
+		if err := srv.CheckCall(tt.tool, tt.args); (err == nil) != tt.ok {
+			t.Errorf("CheckCall(%q, %v) = %v, want ok=%v", tt.tool, tt.args, err, tt.ok)
+		}
+	}
+}
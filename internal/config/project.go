@@ -0,0 +1,228 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ProjectFile is looked up from the working directory towards the root when
+// mounting; its servers are merged over the config's for that mount only.
+const ProjectFile = ".mcpfs.json"
+
+// Project is a parsed ProjectFile. Profile, if set, is used when neither
+// --profile nor MCPFS_PROFILE picks one. Hash is the sha256 of the file it
+// was parsed from.
+type Project struct {
+	Path    string                   `json:"-"`
+	Profile string                   `json:"profile,omitempty"`
+	Hash    string                   `json:"-"`
+	Servers map[string]*ServerConfig `json:"-"`
+}
+
+// trustFile, in the base config dir, maps each ProjectFile the user has
+// trusted to the hash it had then. a project runs commands and picks urls,
+// so one from a cloned repo isn't used until it's trusted, and editing it
+// needs trusting again.
+const trustFile = "trusted-projects.json"
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// DefaultProfile is the profile commands use without --profile.
+func DefaultProfile() string {
+	return os.Getenv("MCPFS_PROFILE")
+}
+
+// ProfileDir is where a profile keeps its servers.json and auth: a config
+// dir of its own under <configDir>/profiles. the empty profile is configDir
+// itself.
+func ProfileDir(configDir, profile string) (string, error) {
+	if configDir == "" {
+		configDir = DefaultConfigDir()
+	}
+	if profile == "" {
+		return configDir, nil
+	}
+	if !profileNamePattern.MatchString(profile) {
+		return "", fmt.Errorf("invalid profile name %q", profile)
+	}
+	return filepath.Join(configDir, "profiles", profile), nil
+}
+
+// FindProject returns the nearest ProjectFile in dir or its parents, or ""
+// if there is none.
+func FindProject(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		p := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(p); err == nil && !info.IsDir() {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads a ProjectFile: {"profile": "work", "servers": {...}}
+// where servers has the servers.json shape.
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", ProjectFile, err)
+	}
+	return parseProject(path, data)
+}
+
+func parseProject(path string, data []byte) (*Project, error) {
+	var doc struct {
+		Profile string          `json:"profile"`
+		Servers json.RawMessage `json:"servers"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	p := &Project{Path: path, Profile: doc.Profile, Hash: hex.EncodeToString(sum[:]), Servers: make(map[string]*ServerConfig)}
+	if len(doc.Servers) > 0 {
+		servers, err := ParseServers(doc.Servers)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		p.Servers = servers
+	}
+	return p, nil
+}
+
+// ProjectTrusted reports whether p was trusted with TrustProject and hasn't
+// changed since.
+func ProjectTrusted(configDir string, p *Project) bool {
+	trusted, err := loadTrusted(configDir)
+	if err != nil {
+		return false
+	}
+	path, err := filepath.Abs(p.Path)
+	return err == nil && trusted[path] == p.Hash
+}
+
+// TrustProject remembers p, as it is now, as safe to use.
+func TrustProject(configDir string, p *Project) error {
+	if configDir == "" {
+		configDir = DefaultConfigDir()
+	}
+	path, err := filepath.Abs(p.Path)
+	if err != nil {
+		return err
+	}
+	trusted, err := loadTrusted(configDir)
+	if err != nil {
+		return err
+	}
+	trusted[path] = p.Hash
+
+	data, err := json.MarshalIndent(trusted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return fmt.Errorf("create config dir: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, trustFile), data, 0600); err != nil {
+		return fmt.Errorf("write %s: %w", trustFile, err)
+	}
+	return nil
+}
+
+func loadTrusted(configDir string) (map[string]string, error) {
+	if configDir == "" {
+		configDir = DefaultConfigDir()
+	}
+	trusted := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(configDir, trustFile))
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", trustFile, err)
+	}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", trustFile, err)
+	}
+	return trusted, nil
+}
+
+// ValidateProject runs Validate on a ProjectFile's servers, checking auth
+// references against configDir.
+func ValidateProject(path, configDir string) []Problem {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []Problem{{Severity: SeverityFatal, Message: err.Error()}}
+	}
+	var doc struct {
+		Servers json.RawMessage `json:"servers"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []Problem{{Severity: SeverityFatal, Message: fmt.Sprintf("parse %s: %v", path, err)}}
+	}
+	if len(doc.Servers) == 0 {
+		return nil
+	}
+	return Validate(doc.Servers, configDir)
+}
+
+// SetProject merges a project's servers over the config. they win over
+// servers of the same name but are never saved; editing one makes it a
+// regular server.
+func (c *Config) SetProject(p *Project) []Change {
+	saved := c.Saved()
+	c.mu.Lock()
+	c.project = p
+	c.mu.Unlock()
+	return c.Replace(saved)
+}
+
+// ProjectPath is the ProjectFile merged into the config, if any.
+func (c *Config) ProjectPath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.project == nil {
+		return ""
+	}
+	return c.project.Path
+}
+
+// FromProject reports whether a server currently comes from the project.
+func (c *Config) FromProject(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fromProject(name)
+}
+
+// Shadows reports whether a project server hides a saved server of the same
+// name. it gets none of that name's stored auth, or a project could point
+// the name at its own url and collect the token.
+func (c *Config) Shadows(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.shadowed[name]
+	return ok && c.fromProject(name)
+}
+
+// fromProject needs c.mu held.
+func (c *Config) fromProject(name string) bool {
+	if c.project == nil {
+		return false
+	}
+	srv, ok := c.project.Servers[name]
+	return ok && c.Servers[name] == srv
+}
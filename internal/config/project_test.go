@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileDir(t *testing.T) {
+	if dir, _ := ProfileDir("/cfg", ""); dir != "/cfg" {
+		t.Errorf("expected base dir, got %s", dir)
+	}
+	if dir, _ := ProfileDir("/cfg", "work"); dir != filepath.Join("/cfg", "profiles", "work") {
+		t.Errorf("unexpected profile dir %s", dir)
+	}
+	for _, bad := range []string{"..", "a/b", ".hidden"} {
+		if _, err := ProfileDir("/cfg", bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestFindProject(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "pkg")
+	os.MkdirAll(nested, 0755)
+
+	if got := FindProject(nested); got != "" {
+		t.Errorf("expected no project, got %s", got)
+	}
+	os.WriteFile(filepath.Join(root, ProjectFile), []byte(`{}`), 0644)
+	if got := FindProject(nested); got != filepath.Join(root, ProjectFile) {
+		t.Errorf("expected project in parent, got %q", got)
+	}
+}
+
+func TestTrustProject(t *testing.T) {
+	configDir := t.TempDir()
+	path := filepath.Join(t.TempDir(), ProjectFile)
+	os.WriteFile(path, []byte(`{"servers": {"@repo/db": {"transport": "stdio", "command": "db"}}}`), 0644)
+
+	project, _ := LoadProject(path)
+	if ProjectTrusted(configDir, project) {
+		t.Error("expected a new project not to be trusted")
+	}
+	if err := TrustProject(configDir, project); err != nil {
+		t.Fatalf("trust: %v", err)
+	}
+	if project, _ = LoadProject(path); !ProjectTrusted(configDir, project) {
+		t.Error("expected trusted project")
+	}
+
+	// any edit needs trusting again
+	os.WriteFile(path, []byte(`{"servers": {"@repo/db": {"transport": "stdio", "command": "evil"}}}`), 0644)
+	if project, _ = LoadProject(path); ProjectTrusted(configDir, project) {
+		t.Error("expected changed project not to be trusted")
+	}
+}
+
+func TestSetProject(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := Load(dir)
+	cfg.AddStdioServer("@a/global", "global", nil, nil)
+	cfg.AddStdioServer("@a/shared", "global-shared", nil, nil)
+	cfg.Save()
+
+	path := filepath.Join(t.TempDir(), ProjectFile)
+	os.WriteFile(path, []byte(`{
+		"profile": "work",
+		"servers": {
+			"@a/shared": {"transport": "stdio", "command": "project-shared"},
+			"@repo/db": {"transport": "stdio", "command": "db"}
+		}
+	}`), 0644)
+	project, err := LoadProject(path)
+	if err != nil {
+		t.Fatalf("load project: %v", err)
+	}
+	if project.Profile != "work" {
+		t.Errorf("expected profile work, got %q", project.Profile)
+	}
+
+	changes := cfg.SetProject(project)
+	if len(changes) != 2 {
+		t.Errorf("expected shared changed and db added, got %+v", changes)
+	}
+	if srv, _ := cfg.GetServer("@a/shared"); srv.Command != "project-shared" {
+		t.Errorf("expected project to win, got %s", srv.Command)
+	}
+	if !cfg.FromProject("@repo/db") || cfg.FromProject("@a/global") {
+		t.Error("unexpected FromProject")
+	}
+	if !cfg.Shadows("@a/shared") || cfg.Shadows("@repo/db") || cfg.Shadows("@a/global") {
+		t.Error("unexpected Shadows")
+	}
+
+	// saving keeps the project out of servers.json
+	cfg.AddStdioServer("@a/new", "new", nil, nil)
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	saved, _ := Load(dir)
+	if _, ok := saved.Servers["@repo/db"]; ok {
+		t.Error("expected project server not saved")
+	}
+	if saved.Servers["@a/shared"].Command != "global-shared" || saved.Servers["@a/new"] == nil {
+		t.Errorf("unexpected saved servers: %v", saved.Servers)
+	}
+
+	// reloading keeps the project merged in
+	if _, err := cfg.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if srv, ok := cfg.GetServer("@repo/db"); !ok || srv.Command != "db" {
+		t.Error("expected project server after reload")
+	}
+
+	// editing a project server makes it a saved one
+	cfg.SetServer("@repo/db", &ServerConfig{Transport: TransportStdio, Command: "db2"})
+	cfg.Save()
+	saved, _ = Load(dir)
+	if saved.Servers["@repo/db"] == nil || saved.Servers["@repo/db"].Command != "db2" {
+		t.Errorf("expected edited server saved, got %v", saved.Servers["@repo/db"])
+	}
+
+	// and the reload its save triggers doesn't bring the project's back,
+	// nor one that was removed
+	cfg.RemoveServer("@a/shared")
+	cfg.Save()
+	if _, err := cfg.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if srv, _ := cfg.GetServer("@repo/db"); srv == nil || srv.Command != "db2" {
+		t.Errorf("expected edit kept after reload, got %v", srv)
+	}
+	if _, ok := cfg.GetServer("@a/shared"); ok {
+		t.Error("expected removed project server to stay removed after reload")
+	}
+}
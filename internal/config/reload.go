@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// Change describes one server that differs between two configs. Old is nil
+// for added servers and New is nil for removed ones.
+type Change struct {
+	Name string
+	Old  *ServerConfig
+	New  *ServerConfig
+}
+
+// RootsOnly reports whether nothing but the roots changed, which a running
+// server can be told about instead of being restarted.
+func (ch Change) RootsOnly() bool {
+	if ch.Old == nil || ch.New == nil {
+		return false
+	}
+	a, b := *ch.Old, *ch.New
+	a.Roots, b.Roots = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// Reload re-reads servers.json and swaps it in, keeping the project merged
+// over it as it was set: the project file is only read at mount, so project
+// servers edited or removed since stay that way. a file that doesn't parse
+// leaves the current servers untouched.
+func (c *Config) Reload() ([]Change, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, "servers.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read servers.json: %w", err)
+	}
+
+	servers := make(map[string]*ServerConfig)
+	if len(data) > 0 {
+		if servers, err = ParseServers(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.Replace(servers), nil
+}
+
+// Replace swaps in a new set of saved servers, merges the project over them
+// and reports what changed.
+func (c *Config) Replace(servers map[string]*ServerConfig) []Change {
+	merged := make(map[string]*ServerConfig, len(servers))
+	for name, srv := range servers {
+		merged[name] = srv
+	}
+
+	c.mu.Lock()
+	c.shadowed = make(map[string]*ServerConfig)
+	if c.project != nil {
+		for name, srv := range c.project.Servers {
+			if hidden, ok := merged[name]; ok {
+				c.shadowed[name] = hidden
+			}
+			merged[name] = srv
+		}
+	}
+	old := c.Servers
+	c.Servers = merged
+	c.mu.Unlock()
+
+	return Diff(old, merged)
+}
+
+// Diff lists servers that were added, removed or modified, sorted by name.
+func Diff(old, new map[string]*ServerConfig) []Change {
+	var changes []Change
+	for name, srv := range old {
+		if n, ok := new[name]; !ok {
+			changes = append(changes, Change{Name: name, Old: srv})
+		} else if !reflect.DeepEqual(srv, n) {
+			changes = append(changes, Change{Name: name, Old: srv, New: n})
+		}
+	}
+	for name, srv := range new {
+		if _, ok := old[name]; !ok {
+			changes = append(changes, Change{Name: name, New: srv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := Load(dir)
+	cfg.AddStdioServer("@a/keep", "keep", nil, nil)
+	cfg.AddStdioServer("@a/gone", "gone", nil, nil)
+	cfg.AddStdioServer("@a/roots", "roots", nil, nil)
+	cfg.AddStdioServer("@a/changed", "old", nil, nil)
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// another process edits the file
+	other, _ := Load(dir)
+	delete(other.Servers, "@a/gone")
+	other.Servers["@a/roots"].Roots = []string{"~/src"}
+	other.Servers["@a/changed"].Command = "new"
+	other.AddHTTPServer("@a/added", "https://example.com/mcp", nil)
+	other.Save()
+
+	changes, err := cfg.Reload()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	want := []struct {
+		name      string
+		added     bool
+		removed   bool
+		rootsOnly bool
+	}{
+		{"@a/added", true, false, false},
+		{"@a/changed", false, false, false},
+		{"@a/gone", false, true, false},
+		{"@a/roots", false, false, true},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i, w := range want {
+		ch := changes[i]
+		if ch.Name != w.name || (ch.Old == nil) != w.added || (ch.New == nil) != w.removed || ch.RootsOnly() != w.rootsOnly {
+			t.Errorf("change %d: got %+v, want %+v", i, ch, w)
+		}
+	}
+
+	if _, ok := cfg.GetServer("@a/gone"); ok {
+		t.Error("expected removed server to be gone")
+	}
+	if srv, _ := cfg.GetServer("@a/changed"); srv.Command != "new" {
+		t.Errorf("expected new command, got %s", srv.Command)
+	}
+}
+
+func TestReloadInvalidKeepsServers(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := Load(dir)
+	cfg.AddStdioServer("@a/keep", "keep", nil, nil)
+	cfg.Save()
+
+	os.WriteFile(filepath.Join(dir, "servers.json"), []byte("{not json"), 0644)
+
+	if _, err := cfg.Reload(); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if _, ok := cfg.GetServer("@a/keep"); !ok {
+		t.Error("expected servers to survive a bad file")
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// values in env, headers and stored auth may reference secrets kept
+// elsewhere:
+//
+//	${auth.token}          stored auth value
+//	${env.GITHUB_TOKEN}    environment of the mcpfs process
+//	${file:~/.secrets/gh}  file contents, trailing whitespace trimmed
+//	${cmd:pass show gh}    stdout of a shell command, run at connect time
+//	${keyring:@gh/mcp/token} secret service entry (see SecretTool)
+var secretVarPattern = regexp.MustCompile(`\$\{(auth\.|env\.|file:|cmd:|keyring:)([^}]*)\}`)
+
+const (
+	AuthStoreFile    = "file"
+	AuthStoreKeyring = "keyring"
+)
+
+// SecretTool is the libsecret cli used for the keyring backend. tests point
+// it at a stub.
+var SecretTool = "secret-tool"
+
+const (
+	keyringService = "mcpfs"
+	commandTimeout = 10 * time.Second
+)
+
+// AuthStore is where SaveAuth puts new values: MCPFS_AUTH_STORE=keyring keeps
+// them out of the config dir entirely.
+func AuthStore() string {
+	if store := os.Getenv("MCPFS_AUTH_STORE"); store != "" {
+		return store
+	}
+	return AuthStoreFile
+}
+
+// IsSecretRef reports whether a value is a single reference rather than the
+// secret itself.
+func IsSecretRef(value string) bool {
+	loc := secretVarPattern.FindStringIndex(value)
+	return loc != nil && loc[0] == 0 && loc[1] == len(value)
+}
+
+// expandVars replaces every reference in s. auth values may be references
+// themselves and are expanded once more, without ${auth.*} to avoid loops.
+func expandVars(s string, auth *Auth) (string, error) {
+	return expand(s, auth, nil)
+}
+
+// expand is expandVars that also hands every value a reference produced to
+// found, so callers learn the secrets without fetching them twice.
+func expand(s string, auth *Auth, found func(string)) (string, error) {
+	var firstErr error
+	note := func(val string) string {
+		if found != nil && val != "" {
+			found(val)
+		}
+		return val
+	}
+	out := secretVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := secretVarPattern.FindStringSubmatch(match)
+		kind, arg := parts[1], parts[2]
+
+		if kind == "auth." {
+			if auth == nil {
+				return match
+			}
+			val, ok := auth.Data[arg]
+			if !ok {
+				return match
+			}
+			if secretVarPattern.MatchString(val) {
+				expanded, err := expand(val, nil, found)
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("auth.%s: %w", arg, err)
+				}
+				return note(expanded)
+			}
+			return note(val)
+		}
+
+		val, err := lookupSecret(kind, arg)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return match
+		}
+		return note(val)
+	})
+	return out, firstErr
+}
+
+func lookupSecret(kind, arg string) (string, error) {
+	switch kind {
+	case "env.":
+		val, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("env %s is not set", arg)
+		}
+		return val, nil
+
+	case "file:":
+		path := arg
+		if path == "~" || strings.HasPrefix(path, "~/") {
+			home, _ := os.UserHomeDir()
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), " \t\r\n"), nil
+
+	case "cmd:":
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "sh", "-c", arg).Output()
+		if err != nil {
+			return "", fmt.Errorf("secret command %q: %w", arg, err)
+		}
+		return strings.TrimRight(string(out), "\r\n"), nil
+
+	case "keyring:":
+		return keyringGet(arg)
+	}
+	return "", fmt.Errorf("unknown secret reference %s", kind)
+}
+
+// Resolve returns a copy of auth with every referenced value fetched. a nil
+// auth resolves to an empty one.
+func (a *Auth) Resolve() (*Auth, error) {
+	if a == nil {
+		return &Auth{Data: make(map[string]string)}, nil
+	}
+	resolved := &Auth{Data: make(map[string]string, len(a.Data))}
+	for key, value := range a.Data {
+		val, err := expandVars(value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("auth.%s: %w", key, err)
+		}
+		resolved.Data[key] = val
+	}
+	return resolved, nil
+}
+
+// keyringAccount is the account a ${keyring:...} reference points at, or "".
+func keyringAccount(value string) string {
+	if !IsSecretRef(value) || !strings.HasPrefix(value, "${keyring:") {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(value, "${keyring:"), "}")
+}
+
+func keyringGet(account string) (string, error) {
+	cmd := exec.Command(SecretTool, "lookup", "service", keyringService, "account", account)
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("keyring lookup %s: %w", account, err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+func keyringSet(account, secret string) error {
+	cmd := exec.Command(SecretTool, "store", "--label", "mcpfs "+account,
+		"service", keyringService, "account", account)
+	cmd.Stdin = strings.NewReader(secret)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("keyring store %s: %w: %s", account, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// moveToKeyring stores plain values in the keyring and leaves references
+// behind, so nothing secret is written under the config dir.
+func moveToKeyring(serverName string, auth *Auth) (*Auth, error) {
+	out := &Auth{Data: make(map[string]string, len(auth.Data))}
+	for key, value := range auth.Data {
+		if IsSecretRef(value) {
+			out.Data[key] = value
+			continue
+		}
+		account := serverName + "/" + key
+		if err := keyringSet(account, value); err != nil {
+			return nil, err
+		}
+		out.Data[key] = "${keyring:" + account + "}"
+	}
+	return out, nil
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandVars(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "token")
+	os.WriteFile(secretFile, []byte("from-file\n"), 0600)
+	t.Setenv("MCPFS_TEST_TOKEN", "from-env")
+
+	auth := &Auth{Data: map[string]string{
+		"plain":    "from-auth",
+		"indirect": "${env.MCPFS_TEST_TOKEN}",
+	}}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"${auth.plain}", "from-auth"},
+		{"${auth.indirect}", "from-env"},
+		{"Bearer ${env.MCPFS_TEST_TOKEN}", "Bearer from-env"},
+		{"${file:" + secretFile + "}", "from-file"},
+		{"${cmd:echo from-cmd}", "from-cmd"},
+		{"${auth.missing}", "${auth.missing}"},
+		{"static", "static"},
+	}
+	for _, tt := range tests {
+		got, err := expandVars(tt.in, auth)
+		if err != nil {
+			t.Errorf("expandVars(%q): %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("expandVars(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"${env.MCPFS_TEST_UNSET}", "${file:" + dir + "/nope}", "${cmd:exit 3}"} {
+		if _, err := expandVars(bad, auth); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+
+	srv := &ServerConfig{Headers: map[string]string{"Authorization": "Bearer ${cmd:exit 1}"}}
+	if _, _, err := srv.Resolve(auth); err == nil || !strings.Contains(err.Error(), "Authorization") {
+		t.Errorf("expected header error, got %v", err)
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "runs")
+	auth := &Auth{Data: map[string]string{
+		"token": "${cmd:echo x >> " + counter + "; echo from-cmd}",
+	}}
+	srv := &ServerConfig{
+		Env:     map[string]string{"TOKEN": "${auth.token}", "MODE": "static"},
+		Headers: map[string]string{"Authorization": "Bearer ${auth.token}"},
+	}
+
+	env, headers, secrets, err := srv.ResolveSecrets(auth)
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if env["TOKEN"] != "from-cmd" || headers["Authorization"] != "Bearer from-cmd" {
+		t.Errorf("unexpected resolution: %v %v", env, headers)
+	}
+	for _, v := range secrets {
+		if v != "from-cmd" {
+			t.Errorf("unexpected secret %q", v)
+		}
+	}
+	if len(secrets) == 0 {
+		t.Error("expected the resolved token among the secrets")
+	}
+
+	// once for env, once for the header; nothing else runs the command
+	data, _ := os.ReadFile(counter)
+	if runs := strings.Count(string(data), "x"); runs != 2 {
+		t.Errorf("expected the command to run twice, ran %d times", runs)
+	}
+
+	var none *Auth
+	if resolved, err := none.Resolve(); err != nil || len(resolved.Data) != 0 {
+		t.Errorf("expected nil auth to resolve to nothing, got %v, %v", resolved, err)
+	}
+}
+
+// stubSecretTool fakes secret-tool with one file per account.
+func stubSecretTool(t *testing.T) string {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "secret-tool")
+	os.WriteFile(script, []byte(`#!/bin/sh
+store="`+dir+`/store"
+mkdir -p "$store"
+cmd=$1; shift
+account=""
+while [ $# -gt 0 ]; do
+  case $1 in
+    --label) shift 2 ;;
+    account) account=$(echo "$2" | tr '/@' '__'); shift 2 ;;
+    *) shift 2 ;;
+  esac
+done
+case $cmd in
+  store) cat > "$store/$account" ;;
+  lookup) cat "$store/$account" 2>/dev/null || exit 1 ;;
+esac
+`), 0755)
+
+	old := SecretTool
+	SecretTool = script
+	t.Cleanup(func() { SecretTool = old })
+	return dir
+}
+
+func TestKeyringStore(t *testing.T) {
+	stubDir := stubSecretTool(t)
+	configDir := t.TempDir()
+
+	auth := &Auth{Data: map[string]string{
+		"token": "ghp_plaintext",
+		"other": "${env.HOME}",
+	}}
+	if err := SaveAuthTo(configDir, "@github/mcp", auth, AuthStoreKeyring); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(configDir, "auth", "github_mcp.json"))
+	if strings.Contains(string(data), "ghp_plaintext") {
+		t.Fatalf("expected no plaintext on disk, got %s", data)
+	}
+	if _, err := os.Stat(filepath.Join(stubDir, "store", "_github_mcp_token")); err != nil {
+		t.Fatalf("expected keyring entry: %v", err)
+	}
+
+	loaded, _ := LoadAuth(configDir, "@github/mcp")
+	if loaded.Data["token"] != "${keyring:@github/mcp/token}" || loaded.Data["other"] != "${env.HOME}" {
+		t.Errorf("unexpected stored refs: %v", loaded.Data)
+	}
+
+	resolved, err := loaded.Resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if resolved.Data["token"] != "ghp_plaintext" {
+		t.Errorf("expected token from keyring, got %q", resolved.Data["token"])
+	}
+}
+
+func TestSaveAuthUpdateKeepsKeyring(t *testing.T) {
+	stubSecretTool(t)
+	t.Setenv("MCPFS_AUTH_STORE", "")
+	configDir := t.TempDir()
+
+	auth := &Auth{Data: map[string]string{"token": "old", "client_id": "${env.HOME}"}}
+	if err := SaveAuthTo(configDir, "@linear/mcp", auth, AuthStoreKeyring); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	stored, _ := LoadAuth(configDir, "@linear/mcp")
+
+	err := SaveAuthUpdate(configDir, "@linear/mcp", stored, map[string]string{
+		"token":         "new-token",
+		"refresh_token": "new-refresh",
+	})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(configDir, "auth", "linear_mcp.json"))
+	if strings.Contains(string(data), "new-") {
+		t.Fatalf("expected no plaintext on disk, got %s", data)
+	}
+	loaded, _ := LoadAuth(configDir, "@linear/mcp")
+	if loaded.Data["token"] != "${keyring:@linear/mcp/token}" || loaded.Data["client_id"] != "${env.HOME}" {
+		t.Errorf("expected references to stay, got %v", loaded.Data)
+	}
+	resolved, err := loaded.Resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if resolved.Data["token"] != "new-token" || resolved.Data["refresh_token"] != "new-refresh" {
+		t.Errorf("unexpected resolved auth: %v", resolved.Data)
+	}
+}
+
+func TestUpdateAuthKeepsKeyring(t *testing.T) {
+	stubSecretTool(t)
+	t.Setenv("MCPFS_AUTH_STORE", "")
+	configDir := t.TempDir()
+
+	auth := &Auth{Data: map[string]string{"token": "old"}}
+	if err := SaveAuthTo(configDir, "@github/mcp", auth, AuthStoreKeyring); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// what a write of "token=rotated" to .auth does
+	update, err := ParseAuthUpdate([]byte("token=rotated\n"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := UpdateAuth(configDir, "@github/mcp", update); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(configDir, "auth", "github_mcp.json"))
+	var onDisk map[string]string
+	json.Unmarshal(data, &onDisk)
+	if len(onDisk) != 1 || onDisk["token"] != "${keyring:@github/mcp/token}" {
+		t.Fatalf("expected only the keyring reference on disk, got %s", data)
+	}
+	resolved, err := (&Auth{Data: onDisk}).Resolve()
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if resolved.Data["token"] != "rotated" {
+		t.Errorf("expected rotated token in the keyring, got %q", resolved.Data["token"])
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Severity string
+
+const (
+	// SeverityFatal problems make a server unusable; mount refuses to start.
+	SeverityFatal Severity = "fatal"
+	// SeverityWarn problems may still work out, e.g. a token not stored yet.
+	SeverityWarn Severity = "warn"
+)
+
+// Problem is one finding of Validate. Server is empty for problems with the
+// file as a whole.
+type Problem struct {
+	Server   string
+	Severity Severity
+	Message  string
+}
+
+func (p Problem) String() string {
+	if p.Server == "" {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Server, p.Message)
+}
+
+// HasFatal reports whether any problem is fatal.
+func HasFatal(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityFatal {
+			return true
+		}
+	}
+	return false
+}
+
+var authRefPattern = regexp.MustCompile(`\$\{auth\.([^}]*)\}`)
+
+// Validate checks the servers.json the config was loaded from.
+func (c *Config) Validate() []Problem {
+	return ValidateDir(c.dir)
+}
+
+// ValidateDir reads servers.json from configDir and reports every problem
+// at once. unlike Load it also works on a file Load would refuse. a missing
+// file is a valid, empty config.
+func ValidateDir(configDir string) []Problem {
+	if configDir == "" {
+		configDir = DefaultConfigDir()
+	}
+	data, err := os.ReadFile(filepath.Join(configDir, "servers.json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return []Problem{{Severity: SeverityFatal, Message: err.Error()}}
+	}
+	return Validate(data, configDir)
+}
+
+// Validate checks a servers.json document: the same rules ParseServers
+// enforces, plus unknown fields, ${auth.x} references missing from the auth
+// store in configDir, names that would share auth and cache files, and
+// commands that aren't on PATH. problems are sorted by server.
+func Validate(data []byte, configDir string) []Problem {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []Problem{{Severity: SeverityFatal, Message: fmt.Sprintf("parse servers.json: %v", err)}}
+	}
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	problems := checkNames(names)
+	for _, name := range names {
+		add := func(severity Severity, format string, args ...any) {
+			problems = append(problems, Problem{Server: name, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+
+		var srv *ServerConfig
+		if err := json.Unmarshal(raw[name], &srv); err != nil {
+			add(SeverityFatal, "%v", err)
+			continue
+		}
+		// a typo like "comand" would otherwise just be ignored
+		dec := json.NewDecoder(bytes.NewReader(raw[name]))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&ServerConfig{}); err != nil && srv != nil {
+			add(SeverityWarn, "%v", err)
+		}
+
+		fatal := checkServer(srv)
+		for _, msg := range fatal {
+			add(SeverityFatal, "%s", msg)
+		}
+		if len(fatal) > 0 {
+			continue
+		}
+
+		for _, msg := range serverWarnings(name, srv, configDir) {
+			add(SeverityWarn, "%s", msg)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Server < problems[j].Server
+	})
+	return problems
+}
+
+// Check returns the first problem that would keep the config from loading
+// once srv is saved as name, so commands can refuse an entry before writing
+// it rather than leave a servers.json nothing can read.
+func (c *Config) Check(name string, srv *ServerConfig) error {
+	saved := c.Saved()
+	saved[name] = srv
+	names := make([]string, 0, len(saved))
+	for n := range saved {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	// the rest loaded, so any clash is with the new entry
+	for _, p := range checkNames(names) {
+		if p.Severity == SeverityFatal {
+			return fmt.Errorf("%s: %s", p.Server, p.Message)
+		}
+	}
+	if problems := checkServer(srv); len(problems) > 0 {
+		return fmt.Errorf("%s: %s", name, problems[0])
+	}
+	return nil
+}
+
+// checkServer lists what makes a server impossible to start.
+func checkServer(srv *ServerConfig) []string {
+	if srv == nil {
+		return []string{"empty server config"}
+	}
+
+	var problems []string
+	switch srv.Transport {
+	case TransportStdio:
+		if srv.Command == "" {
+			problems = append(problems, "stdio server needs a command")
+		}
+	case TransportHTTP:
+		if srv.URL == "" {
+			problems = append(problems, "http server needs a url")
+		} else if u, err := url.Parse(srv.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("url %q is not an http(s) url", srv.URL))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown transport %q", srv.Transport))
+	}
+
+	for _, pattern := range srv.AuthErrors {
+		if _, err := regexp.Compile(pattern); err != nil {
+			problems = append(problems, fmt.Sprintf("authErrors: %v", err))
+		}
+	}
+	return problems
+}
+
+// checkNames finds names the mount can't show and names that map to the
+// same auth and cache files, also on case-insensitive filesystems.
+func checkNames(names []string) []Problem {
+	var problems []Problem
+	seen := make(map[string]string)
+	for _, name := range names {
+		scope, server := ParseServerName(name)
+		switch {
+		case scope == "":
+			problems = append(problems, Problem{Server: name, Severity: SeverityWarn, Message: "name has no @scope/ and won't get a directory in the mount"})
+		case server == "" || strings.Contains(server, "/") || !strings.HasPrefix(name, "@"):
+			problems = append(problems, Problem{Server: name, Severity: SeverityFatal, Message: "name must look like @scope/name"})
+			continue
+		}
+
+		key := strings.ToLower(SafeServerName(name))
+		if other, ok := seen[key]; ok {
+			problems = append(problems, Problem{Server: name, Severity: SeverityFatal, Message: fmt.Sprintf("collides with %s: both would use auth/%s.json", other, SafeServerName(name))})
+			continue
+		}
+		seen[key] = name
+	}
+	return problems
+}
+
+func serverWarnings(name string, srv *ServerConfig, configDir string) []string {
+	var warnings []string
+
+	if srv.Transport == TransportStdio {
+		if _, err := exec.LookPath(srv.Command); err != nil {
+			warnings = append(warnings, fmt.Sprintf("command %q not found on PATH", srv.Command))
+		}
+	}
+
+	if auth, err := LoadAuth(configDir, name); err != nil {
+		warnings = append(warnings, err.Error())
+	} else {
+		var values []string
+		for _, v := range srv.Env {
+			values = append(values, v)
+		}
+		for _, v := range srv.Headers {
+			values = append(values, v)
+		}
+		missing := make(map[string]bool)
+		for _, v := range values {
+			for _, m := range authRefPattern.FindAllStringSubmatch(v, -1) {
+				if _, ok := auth.Data[m[1]]; !ok {
+					missing[m[1]] = true
+				}
+			}
+		}
+		for _, key := range sortedSet(missing) {
+			warnings = append(warnings, fmt.Sprintf("${auth.%s} is not in the auth store; run mcpfs auth %s or write %s/.auth", key, name, name))
+		}
+	}
+
+	for _, pattern := range sortedKeys(srv.Cache) {
+		if _, err := time.ParseDuration(srv.Cache[pattern]); err != nil {
+			warnings = append(warnings, fmt.Sprintf("cache %s: bad ttl %q", pattern, srv.Cache[pattern]))
+		}
+	}
+	for _, pattern := range append(append([]string{}, srv.AllowTools...), srv.DenyTools...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			warnings = append(warnings, fmt.Sprintf("tool pattern %q: %v", pattern, err))
+		}
+	}
+	return warnings
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
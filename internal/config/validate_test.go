@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	SaveTokenTo(dir, "@ok/http", "t", AuthStoreFile)
+
+	data := []byte(`{
+		"@ok/http": {"transport": "http", "url": "https://example.com/mcp", "headers": {"Authorization": "Bearer ${auth.token}"}},
+		"@ok/stdio": {"transport": "stdio", "command": "sh"},
+		"@bad/nocmd": {"transport": "stdio"},
+		"@bad/nourl": {"transport": "http"},
+		"@bad/ftp": {"transport": "http", "url": "ftp://example.com"},
+		"@bad/transport": {"transport": "grpc"},
+		"@bad/types": {"transport": "stdio", "command": 42},
+		"@Dup/one": {"transport": "stdio", "command": "sh"},
+		"@dup/one": {"transport": "stdio", "command": "sh"},
+		"@warn/typo": {"transport": "stdio", "comand": "x", "command": "sh"},
+		"@warn/missing": {"transport": "stdio", "command": "definitely-not-installed-mcpfs", "env": {"TOKEN": "${auth.token}"}},
+		"@warn/ttl": {"transport": "stdio", "command": "sh", "cache": {"*": "soon"}},
+		"@a/b/c": {"transport": "stdio", "command": "sh"},
+		"unscoped": {"transport": "stdio", "command": "sh"}
+	}`)
+
+	problems := Validate(data, dir)
+	got := make(map[string][]Problem)
+	for _, p := range problems {
+		got[p.Server] = append(got[p.Server], p)
+	}
+
+	for _, name := range []string{"@ok/http", "@ok/stdio"} {
+		if len(got[name]) != 0 {
+			t.Errorf("%s: expected no problems, got %v", name, got[name])
+		}
+	}
+	for _, name := range []string{"@bad/nocmd", "@bad/nourl", "@bad/ftp", "@bad/transport", "@bad/types", "@dup/one", "@a/b/c"} {
+		if len(got[name]) != 1 || got[name][0].Severity != SeverityFatal {
+			t.Errorf("%s: expected one fatal problem, got %v", name, got[name])
+		}
+	}
+	if len(got["@warn/missing"]) != 2 {
+		t.Errorf("expected missing command and auth warnings, got %v", got["@warn/missing"])
+	}
+	for _, name := range []string{"@warn/typo", "@warn/ttl", "unscoped"} {
+		if len(got[name]) != 1 || got[name][0].Severity != SeverityWarn {
+			t.Errorf("%s: expected one warning, got %v", name, got[name])
+		}
+	}
+	if !HasFatal(problems) {
+		t.Error("expected HasFatal")
+	}
+
+	if problems := Validate([]byte(`{"@a/b": `), dir); len(problems) != 1 || !strings.Contains(problems[0].String(), "parse servers.json") {
+		t.Errorf("expected a parse problem, got %v", problems)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := Load(dir)
+	if problems := cfg.Validate(); problems != nil {
+		t.Errorf("expected no problems without servers.json, got %v", problems)
+	}
+
+	os.WriteFile(filepath.Join(dir, "servers.json"), []byte(`{"@a/b": {"transport": "stdio"}}`), 0644)
+	if problems := cfg.Validate(); !HasFatal(problems) {
+		t.Errorf("expected a fatal problem, got %v", problems)
+	}
+	if _, err := Load(dir); err == nil {
+		t.Error("expected Load to refuse a server without a command")
+	}
+}
+
+func TestCheckBeforeSave(t *testing.T) {
+	dir := t.TempDir()
+	cfg, _ := Load(dir)
+	cfg.AddStdioServer("@a/one", "one", nil, nil)
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if err := cfg.Check("@a/two", &ServerConfig{Transport: TransportStdio, Command: "two"}); err != nil {
+		t.Errorf("expected a valid entry to pass, got %v", err)
+	}
+	bad := map[string]*ServerConfig{
+		"notion/mcp": {Transport: TransportStdio, Command: "cmd"},
+		"@A/one":     {Transport: TransportStdio, Command: "cmd"},
+		"@a/url":     {Transport: TransportHTTP, URL: "${env.URL}"},
+	}
+	for name, srv := range bad {
+		if err := cfg.Check(name, srv); err == nil {
+			t.Errorf("expected %s to be refused", name)
+		}
+	}
+
+	// whatever gets past Check, Save won't write a file Load can't read
+	cfg.SetServer("notion/mcp", bad["notion/mcp"])
+	if err := cfg.Save(); err == nil {
+		t.Error("expected save to refuse an invalid name")
+	}
+	if _, err := Load(dir); err != nil {
+		t.Errorf("expected the saved config to still load, got %v", err)
+	}
+}
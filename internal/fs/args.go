@@ -0,0 +1,137 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/winfsp/cgofuse/fuse"
+
+	"github.com/caffeinum/mcpfs/internal/schema"
+)
+
+// args/<property> files hold raw argument values per tool directory. writing
+// .run coerces them with the tool's input schema and calls the tool.
+
+func (fs *CgoFS) toolSchema(serverName, toolName string) (*schema.Schema, error) {
+	conn, err := fs.pool.GetConnection(context.Background(), serverName)
+	if err != nil {
+		return nil, err
+	}
+	tool, ok := findTool(conn.GetTools(), toolName)
+	if !ok {
+		return nil, fmt.Errorf("tool not found: %s", toolName)
+	}
+	return schema.Parse(tool.InputSchema)
+}
+
+// argNames lists schema properties plus anything written that the schema
+// doesn't mention.
+func (fs *CgoFS) argNames(toolPath, serverName, toolName string) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	if sch, err := fs.toolSchema(serverName, toolName); err == nil {
+		for _, name := range sch.PropertyNames() {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	fs.mu.RLock()
+	var extra []string
+	for name := range fs.args[toolPath] {
+		if !seen[name] {
+			extra = append(extra, name)
+		}
+	}
+	fs.mu.RUnlock()
+
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+func (fs *CgoFS) hasArg(toolPath, serverName, toolName, name string) bool {
+	for _, n := range fs.argNames(toolPath, serverName, toolName) {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (fs *CgoFS) argValue(toolPath, name string) []byte {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.args[toolPath][name]
+}
+
+func (fs *CgoFS) writeArg(toolPath, name string, buff []byte, ofst int64) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.args[toolPath] == nil {
+		fs.args[toolPath] = make(map[string][]byte)
+	}
+	value := fs.args[toolPath][name]
+	if end := ofst + int64(len(buff)); end > int64(len(value)) {
+		value = append(value, make([]byte, end-int64(len(value)))...)
+	}
+	copy(value[ofst:], buff)
+	fs.args[toolPath][name] = value
+
+	return len(buff)
+}
+
+func (fs *CgoFS) truncateArg(toolPath, name string, size int64) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.args[toolPath] == nil {
+		fs.args[toolPath] = make(map[string][]byte)
+	}
+	value := fs.args[toolPath][name]
+	if size <= int64(len(value)) {
+		value = value[:size]
+	} else {
+		value = append(value, make([]byte, size-int64(len(value)))...)
+	}
+	fs.args[toolPath][name] = value
+
+	return 0
+}
+
+func (fs *CgoFS) unlinkArg(toolPath, name string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.args[toolPath][name]; !ok {
+		return -fuse.ENOENT
+	}
+	delete(fs.args[toolPath], name)
+	return 0
+}
+
+// run builds the arguments from args/ and calls the tool. the result lands
+// in .result just like a .call write.
+func (fs *CgoFS) run(toolPath, serverName, toolName string) int {
+	sch, err := fs.toolSchema(serverName, toolName)
+	if err != nil {
+		return -fuse.EIO
+	}
+
+	fs.mu.RLock()
+	raw := make(map[string]string, len(fs.args[toolPath]))
+	for name, value := range fs.args[toolPath] {
+		raw[name] = string(value)
+	}
+	fs.mu.RUnlock()
+
+	args, err := sch.BuildArgs(raw)
+	if err != nil {
+		fs.setResult(toolPath+"/.call", errorResult(err.Error()))
+		return -fuse.EINVAL
+	}
+
+	return fs.callTool(toolPath+"/.call", serverName, toolName, args)
+}
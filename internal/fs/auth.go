@@ -0,0 +1,54 @@
+package fs
+
+import (
+	"strings"
+
+	"github.com/winfsp/cgofuse/fuse"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+)
+
+// .auth is write-only: key=value lines or a json object are merged into the
+// server's stored auth when the file is closed, and the server reconnects
+// with them. reading it lists the key names and nothing else.
+
+const authFile = ".auth"
+
+func isAuthFile(parts []string) bool {
+	return len(parts) == 3 && parts[0] != ".config" && parts[2] == authFile
+}
+
+func noContent() []byte { return []byte{} }
+
+func (fs *CgoFS) authKeys(serverName string) []byte {
+	auth, err := config.LoadAuth(fs.cfg.Dir(), serverName)
+	if err != nil || len(auth.Data) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(auth.Keys(), "\n") + "\n")
+}
+
+func (fs *CgoFS) commitAuth(fh uint64, serverName string) int {
+	data := fs.takeEdit(fh)
+	if data == nil {
+		return 0
+	}
+
+	update, err := config.ParseAuthUpdate(data)
+	if err != nil {
+		fs.setConfigError(serverName, "auth: "+err.Error())
+		return -fuse.EINVAL
+	}
+
+	// values go back to the store they came from, so a token kept in the
+	// keyring stays there
+	if err := config.UpdateAuth(fs.cfg.Dir(), serverName, update); err != nil {
+		fs.setConfigError(serverName, "auth: "+err.Error())
+		return -fuse.EIO
+	}
+
+	// the next access reconnects with the new values
+	fs.pool.CloseConnection(serverName)
+	fs.setConfigError(serverName, "")
+	return 0
+}
@@ -3,58 +3,139 @@ package fs
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/winfsp/cgofuse/fuse"
 
+	"github.com/caffeinum/mcpfs/internal/audit"
+	"github.com/caffeinum/mcpfs/internal/cache"
 	"github.com/caffeinum/mcpfs/internal/config"
+	"github.com/caffeinum/mcpfs/internal/history"
 	"github.com/caffeinum/mcpfs/internal/mcp"
 	"github.com/caffeinum/mcpfs/internal/pool"
+	"github.com/caffeinum/mcpfs/internal/redact"
+	"github.com/caffeinum/mcpfs/internal/result"
+	"github.com/caffeinum/mcpfs/internal/schema"
 )
 
+// entries shown in /.audit; the log on disk keeps everything
+const auditRecent = 200
+
+// views of the last result in a tool directory, plus the .result.pages dir
+var resultViewNames = []string{
+	".result", ".result.json", ".result.ndjson", ".result.md",
+	".result.head", ".result.summary", ".result.query",
+}
+
+type Options struct {
+	HeadLines    int // lines in .result.head
+	PageSize     int // bytes per .result.pages entry
+	HistoryLimit int // calls kept per tool under history/, <0 disables
+}
+
 type CgoFS struct {
 	fuse.FileSystemBase
-	cfg     *config.Config
-	pool    *pool.Pool
-	mu      sync.RWMutex
-	results map[string]*mcp.ToolResult // path -> result cache
+	cfg         *config.Config
+	pool        *pool.Pool
+	opts        Options
+	history     *history.Store
+	cache       *cache.Cache
+	auditLog    *audit.Log
+	mu          sync.RWMutex
+	results     map[string]*mcp.ToolResult   // path -> result cache
+	completions map[string][]string          // .complete path -> suggestions
+	completeErr map[string]string            // .complete path -> why there are none
+	args        map[string]map[string][]byte // tool path -> property -> raw value
+	queries     map[string]*result.Query     // tool path -> .query filter
+	queryErrs   map[string]string            // tool path -> rejected .query
+	confirmed   map[string]bool              // tool path -> next destructive call allowed
+	edits       map[uint64]*edit             // file handle -> buffered config write
+	nextFh      uint64
+	configErrs  map[string]string // server ("" for servers.json) -> rejected edit
+	pending     map[string]bool   // servers made with mkdir, waiting for .config.json
+	pendingDirs map[string]bool   // scopes made with mkdir, still empty
+	elicits     *elicitQueue
 }
 
-func NewCgoFS(cfg *config.Config, p *pool.Pool) *CgoFS {
-	return &CgoFS{
-		cfg:     cfg,
-		pool:    p,
-		results: make(map[string]*mcp.ToolResult),
+func NewCgoFS(cfg *config.Config, p *pool.Pool, opts Options) *CgoFS {
+	if opts.HeadLines == 0 {
+		opts.HeadLines = 20
+	}
+	if opts.PageSize == 0 {
+		opts.PageSize = result.TokensToBytes(4000)
+	}
+	if opts.HistoryLimit == 0 {
+		opts.HistoryLimit = 100
+	}
+
+	fs := &CgoFS{
+		cfg:         cfg,
+		pool:        p,
+		opts:        opts,
+		results:     make(map[string]*mcp.ToolResult),
+		completions: make(map[string][]string),
+		completeErr: make(map[string]string),
+		args:        make(map[string]map[string][]byte),
+		queries:     make(map[string]*result.Query),
+		queryErrs:   make(map[string]string),
+		confirmed:   make(map[string]bool),
+		edits:       make(map[uint64]*edit),
+		configErrs:  make(map[string]string),
+		pending:     make(map[string]bool),
+		pendingDirs: make(map[string]bool),
+		elicits:     newElicitQueue(elicitTimeout),
+		cache:       cache.New(filepath.Join(cfg.Dir(), "cache")),
+		auditLog:    audit.New(filepath.Join(cfg.Dir(), "audit.jsonl")),
 	}
+	if opts.HistoryLimit > 0 {
+		fs.history = history.New(filepath.Join(cfg.Dir(), "history"), opts.HistoryLimit)
+	}
+	p.SetElicitHandler(fs.elicits.ask)
+	return fs
 }
 
 func (fs *CgoFS) Getattr(path string, stat *fuse.Stat_t, fh uint64) int {
 	parts := splitPath(path)
+	if fs.inDeniedTool(parts) {
+		return -fuse.ENOENT
+	}
 
 	switch len(parts) {
 	case 0: // root
 		stat.Mode = fuse.S_IFDIR | 0755
 		return 0
 
-	case 1: // scope or .config
+	case 1: // scope, .config or .audit
 		name := parts[0]
 		if name == ".config" || fs.hasScope(name) {
 			stat.Mode = fuse.S_IFDIR | 0755
 			return 0
 		}
+		if name == ".audit" {
+			stat.Mode = fuse.S_IFREG | 0444
+			stat.Size = int64(len(fs.getFileContent(path)))
+			return 0
+		}
 
 	case 2: // server dir or config files
 		if parts[0] == ".config" {
-			if parts[1] == "servers.json" {
-				data, _ := config.MarshalServers(fs.cfg.Servers)
+			if parts[1] == serversFile {
 				stat.Mode = fuse.S_IFREG | 0644
-				stat.Size = int64(len(data))
+				stat.Size = int64(len(fs.editContent(fh, fs.serversContent)))
+				return 0
+			}
+			if parts[1] == serversErrorFile {
+				stat.Mode = fuse.S_IFREG | 0444
+				stat.Size = int64(len(fs.serversError()))
 				return 0
 			}
 		} else {
 			serverName := parts[0] + "/" + parts[1]
-			if _, ok := fs.cfg.Servers[serverName]; ok {
+			if _, ok := fs.cfg.GetServer(serverName); ok || fs.isPendingServer(serverName) {
 				stat.Mode = fuse.S_IFDIR | 0755
 				return 0
 			}
@@ -62,33 +143,63 @@ func (fs *CgoFS) Getattr(path string, stat *fuse.Stat_t, fh uint64) int {
 
 	case 3: // .status, .schema, or tool dir
 		serverName := parts[0] + "/" + parts[1]
-		if _, ok := fs.cfg.Servers[serverName]; !ok {
-			return -fuse.ENOENT
-		}
-
 		name := parts[2]
-		if name == ".status" || name == ".schema" {
+
+		if _, ok := fs.cfg.GetServer(serverName); !ok {
+			if !fs.isPendingServer(serverName) || name != serverConfigFile && name != ".status" {
+				return -fuse.ENOENT
+			}
+		}
+		if name == serverConfigFile {
+			stat.Mode = fuse.S_IFREG | 0644
+			stat.Size = int64(len(fs.editContent(fh, func() []byte { return fs.serverConfigContent(serverName) })))
+			return 0
+		}
+		if name == authFile {
+			// write-only: the key list isn't content to append to
+			stat.Mode = fuse.S_IFREG | 0200
+			stat.Size = 0
+			return 0
+		}
+		if name == ".status" || name == ".schema" || name == ".log" {
 			stat.Mode = fuse.S_IFREG | 0444
 			stat.Size = int64(len(fs.getFileContent(path)))
 			return 0
 		}
+		if name == ".elicit" {
+			stat.Mode = fuse.S_IFDIR | 0755
+			return 0
+		}
+		if name == ".roots" {
+			stat.Mode = fuse.S_IFREG | 0666
+			stat.Size = int64(len(fs.getFileContent(path)))
+			return 0
+		}
 
 		// check if it's a tool
 		conn, err := fs.pool.GetConnection(context.Background(), serverName)
 		if err != nil {
 			return -fuse.EIO
 		}
-		for _, tool := range conn.GetTools() {
+		for _, tool := range fs.visibleTools(serverName, conn.GetTools()) {
 			if tool.Name == name {
 				stat.Mode = fuse.S_IFDIR | 0755
 				return 0
 			}
 		}
 
-	case 4: // tool files: .schema, .call, .result
+	case 4: // tool files: .schema, .call, .result, or a pending question
+		if parts[2] == ".elicit" {
+			if fs.elicits.get(parts[0]+"/"+parts[1], parts[3]) != nil {
+				stat.Mode = fuse.S_IFDIR | 0755
+				return 0
+			}
+			return -fuse.ENOENT
+		}
+
 		fileName := parts[3]
 
-		if fileName == ".schema" || fileName == ".result" {
+		if fileName == ".schema" || fileName == ".query.error" || fs.resultView(toolPath(parts), fileName) != nil {
 			stat.Mode = fuse.S_IFREG | 0444
 			stat.Size = int64(len(fs.getFileContent(path)))
 			return 0
@@ -98,22 +209,103 @@ func (fs *CgoFS) Getattr(path string, stat *fuse.Stat_t, fh uint64) int {
 			stat.Size = 0
 			return 0
 		}
+		if fileName == ".nocache" {
+			stat.Mode = fuse.S_IFREG | 0222
+			stat.Size = 0
+			return 0
+		}
+		if fileName == ".complete" || fileName == ".query" || fileName == ".confirm" {
+			stat.Mode = fuse.S_IFREG | 0666
+			stat.Size = int64(len(fs.getFileContent(path)))
+			return 0
+		}
+		if fileName == ".run" {
+			stat.Mode = fuse.S_IFREG | 0222
+			stat.Size = 0
+			return 0
+		}
+		if fileName == ".replay" && fs.history != nil {
+			stat.Mode = fuse.S_IFREG | 0222
+			stat.Size = 0
+			return 0
+		}
+		if fileName == "args" || fileName == ".result.pages" || fileName == "history" && fs.history != nil {
+			stat.Mode = fuse.S_IFDIR | 0755
+			return 0
+		}
+
+	case 5: // .elicit/<id>/message, schema, response, args/<property>, a page, or history/<id>
+		if parts[3] == "history" && parts[2] != ".elicit" {
+			if fs.history == nil {
+				return -fuse.ENOENT
+			}
+			if _, err := fs.history.Get(parts[0]+"/"+parts[1], parts[2], parts[4]); err != nil {
+				return -fuse.ENOENT
+			}
+			stat.Mode = fuse.S_IFDIR | 0755
+			return 0
+		}
+		if parts[3] == ".result.pages" && parts[2] != ".elicit" {
+			if fs.resultPage(parts) == nil {
+				return -fuse.ENOENT
+			}
+			stat.Mode = fuse.S_IFREG | 0444
+			stat.Size = int64(len(fs.resultPage(parts)))
+			return 0
+		}
+		if parts[3] == "args" && parts[2] != ".elicit" {
+			if !fs.hasArg(toolPath(parts), parts[0]+"/"+parts[1], parts[2], parts[4]) {
+				return -fuse.ENOENT
+			}
+			stat.Mode = fuse.S_IFREG | 0666
+			stat.Size = int64(len(fs.argValue(toolPath(parts), parts[4])))
+			return 0
+		}
+		if parts[2] != ".elicit" || fs.elicits.get(parts[0]+"/"+parts[1], parts[3]) == nil {
+			return -fuse.ENOENT
+		}
+		switch parts[4] {
+		case "message", "schema":
+			stat.Mode = fuse.S_IFREG | 0444
+			stat.Size = int64(len(fs.getFileContent(path)))
+			return 0
+		case "response":
+			stat.Mode = fuse.S_IFREG | 0666
+			stat.Size = 0
+			return 0
+		}
+
+	case 6: // history/<id>/args.json, result.json, meta.json
+		if parts[3] != "history" || fs.history == nil {
+			return -fuse.ENOENT
+		}
+		data, err := fs.history.ReadFile(parts[0]+"/"+parts[1], parts[2], parts[4], parts[5])
+		if err != nil {
+			return -fuse.ENOENT
+		}
+		stat.Mode = fuse.S_IFREG | 0444
+		stat.Size = int64(len(data))
+		return 0
 	}
 
 	return -fuse.ENOENT
 }
 
 func (fs *CgoFS) Readdir(path string, fill func(name string, stat *fuse.Stat_t, ofst int64) bool, ofst int64, fh uint64) int {
+	parts := splitPath(path)
+	if fs.inDeniedTool(parts) {
+		return -fuse.ENOENT
+	}
+
 	fill(".", nil, 0)
 	fill("..", nil, 0)
 
-	parts := splitPath(path)
-
 	switch len(parts) {
 	case 0: // root
 		fill(".config", nil, 0)
+		fill(".audit", nil, 0)
 		scopes := make(map[string]bool)
-		for name := range fs.cfg.Servers {
+		for _, name := range fs.cfg.Names() {
 			scope, _ := config.ParseServerName(name)
 			if scope != "" && !scopes[scope] {
 				scopes[scope] = true
@@ -121,21 +313,29 @@ func (fs *CgoFS) Readdir(path string, fill func(name string, stat *fuse.Stat_t,
 			}
 		}
 		// also add servers without scope
-		for name := range fs.cfg.Servers {
+		for _, name := range fs.cfg.Names() {
 			scope, _ := config.ParseServerName(name)
 			if scope == "" {
 				fill(name, nil, 0)
 			}
 		}
+		for _, name := range fs.pendingNames() {
+			scope, _ := config.ParseServerName(name)
+			if !scopes[scope] {
+				scopes[scope] = true
+				fill(scope, nil, 0)
+			}
+		}
 
 	case 1: // scope or .config
 		if parts[0] == ".config" {
-			fill("servers.json", nil, 0)
+			fill(serversFile, nil, 0)
+			fill(serversErrorFile, nil, 0)
 		} else {
 			scope := parts[0]
-			for name := range fs.cfg.Servers {
+			for _, name := range append(fs.cfg.Names(), fs.pendingNames()...) {
 				s, server := config.ParseServerName(name)
-				if s == scope {
+				if s == scope && server != "" {
 					fill(server, nil, 0)
 				}
 			}
@@ -147,19 +347,79 @@ func (fs *CgoFS) Readdir(path string, fill func(name string, stat *fuse.Stat_t,
 		}
 		serverName := parts[0] + "/" + parts[1]
 		fill(".status", nil, 0)
+		fill(serverConfigFile, nil, 0)
+		if fs.isPendingServer(serverName) {
+			return 0
+		}
+		fill(".log", nil, 0)
+		fill(authFile, nil, 0)
 		fill(".schema", nil, 0)
+		fill(".elicit", nil, 0)
+		fill(".roots", nil, 0)
 
 		conn, err := fs.pool.GetConnection(context.Background(), serverName)
 		if err == nil {
-			for _, tool := range conn.GetTools() {
+			for _, tool := range fs.visibleTools(serverName, conn.GetTools()) {
 				fill(tool.Name, nil, 0)
 			}
 		}
 
-	case 3: // tool dir
+	case 3: // tool dir or pending questions
+		if parts[2] == ".elicit" {
+			for _, id := range fs.elicits.ids(parts[0] + "/" + parts[1]) {
+				fill(id, nil, 0)
+			}
+			return 0
+		}
+		if srv, ok := fs.cfg.GetServer(parts[0] + "/" + parts[1]); ok && srv.ToolAllowed(parts[2]) != nil {
+			return -fuse.ENOENT
+		}
 		fill(".schema", nil, 0)
 		fill(".call", nil, 0)
-		fill(".result", nil, 0)
+		fill(".nocache", nil, 0)
+		for _, name := range resultViewNames {
+			fill(name, nil, 0)
+		}
+		fill(".result.pages", nil, 0)
+		fill(".complete", nil, 0)
+		fill(".query", nil, 0)
+		fill(".query.error", nil, 0)
+		fill(".run", nil, 0)
+		fill(".confirm", nil, 0)
+		fill("args", nil, 0)
+		if fs.history != nil {
+			fill("history", nil, 0)
+			fill(".replay", nil, 0)
+		}
+
+	case 5: // a history entry
+		if parts[3] == "history" && parts[2] != ".elicit" {
+			fill(history.ArgsFile, nil, 0)
+			fill(history.ResultFile, nil, 0)
+			fill(history.MetaFile, nil, 0)
+		}
+
+	case 4: // a pending question or args dir
+		if parts[2] == ".elicit" {
+			fill("message", nil, 0)
+			fill("schema", nil, 0)
+			fill("response", nil, 0)
+		} else if parts[3] == "args" {
+			for _, name := range fs.argNames(toolPath(parts), parts[0]+"/"+parts[1], parts[2]) {
+				fill(name, nil, 0)
+			}
+		} else if parts[3] == "history" && fs.history != nil {
+			for _, id := range fs.history.List(parts[0]+"/"+parts[1], parts[2]) {
+				fill(id, nil, 0)
+			}
+		} else if parts[3] == ".result.pages" {
+			if res := fs.lastResult(toolPath(parts)); res != nil {
+				pages := result.Pages(res, fs.opts.PageSize)
+				for i := range pages {
+					fill(result.PageName(i, len(pages)), nil, 0)
+				}
+			}
+		}
 	}
 
 	return 0
@@ -167,14 +427,34 @@ func (fs *CgoFS) Readdir(path string, fill func(name string, stat *fuse.Stat_t,
 
 func (fs *CgoFS) Open(path string, flags int) (int, uint64) {
 	parts := splitPath(path)
-	if len(parts) < 2 {
+	if fs.inDeniedTool(parts) {
+		return -fuse.ENOENT, 0
+	}
+	if len(parts) < 2 && !(len(parts) == 1 && parts[0] == ".audit") {
 		return -fuse.ENOENT, 0
 	}
+	if isEditable(parts) {
+		return 0, fs.openEdit(path)
+	}
 	return 0, 0
 }
 
+// Flush commits a config edit so a bad one fails close(2).
+func (fs *CgoFS) Flush(path string, fh uint64) int {
+	return fs.commitEdit(path, fh)
+}
+
+func (fs *CgoFS) Release(path string, fh uint64) int {
+	errno := fs.commitEdit(path, fh)
+	fs.closeEdit(fh)
+	return errno
+}
+
 func (fs *CgoFS) Read(path string, buff []byte, ofst int64, fh uint64) int {
 	data := fs.getFileContent(path)
+	if pending := fs.pendingEdit(fh); pending != nil && !isAuthFile(splitPath(path)) {
+		data = pending
+	}
 	if data == nil {
 		return -fuse.ENOENT
 	}
@@ -189,63 +469,517 @@ func (fs *CgoFS) Read(path string, buff []byte, ofst int64, fh uint64) int {
 
 func (fs *CgoFS) Write(path string, buff []byte, ofst int64, fh uint64) int {
 	parts := splitPath(path)
-	if len(parts) != 4 || parts[3] != ".call" {
+	if fs.inDeniedTool(parts) {
+		return -fuse.ENOENT
+	}
+	if isServersFile(parts) {
+		return fs.writeEdit(fh, fs.serversContent, buff, ofst)
+	}
+	if isAuthFile(parts) {
+		return fs.writeEdit(fh, noContent, buff, ofst)
+	}
+	if isServerConfigFile(parts) {
+		serverName := parts[0] + "/" + parts[1]
+		return fs.writeEdit(fh, func() []byte { return fs.serverConfigContent(serverName) }, buff, ofst)
+	}
+	if len(parts) == 5 && parts[2] == ".elicit" && parts[4] == "response" {
+		if err := fs.elicits.respond(parts[0]+"/"+parts[1], parts[3], buff); err != nil {
+			return -fuse.EINVAL
+		}
+		return len(buff)
+	}
+	if len(parts) == 5 && parts[3] == "args" {
+		return fs.writeArg(toolPath(parts), parts[4], buff, ofst)
+	}
+	if len(parts) == 3 && parts[2] == ".roots" {
+		return fs.writeRoots(parts[0]+"/"+parts[1], buff)
+	}
+	if len(parts) == 4 && parts[3] == ".complete" {
+		return fs.writeComplete(path, parts[0]+"/"+parts[1], parts[2], buff)
+	}
+	if len(parts) == 4 && parts[3] == ".query" {
+		return fs.writeQuery(toolPath(parts), buff)
+	}
+	if len(parts) == 4 && parts[3] == ".confirm" {
+		return fs.writeConfirm(toolPath(parts), buff)
+	}
+	if len(parts) == 4 && parts[3] == ".replay" && fs.history != nil {
+		if errno := fs.replay(toolPath(parts), parts[0]+"/"+parts[1], parts[2], buff); errno != 0 {
+			return errno
+		}
+		return len(buff)
+	}
+	if len(parts) == 4 && parts[3] == ".run" {
+		if errno := fs.run(toolPath(parts), parts[0]+"/"+parts[1], parts[2]); errno != 0 {
+			return errno
+		}
+		return len(buff)
+	}
+	if len(parts) != 4 || parts[3] != ".call" && parts[3] != ".nocache" {
 		return -fuse.EACCES
 	}
 
 	serverName := parts[0] + "/" + parts[1]
 	toolName := parts[2]
+	callPath := toolPath(parts) + "/.call"
 
 	var args map[string]any
 	if err := json.Unmarshal(buff, &args); err != nil {
+		fs.setResult(callPath, errorResult("invalid json: "+err.Error()))
 		return -fuse.EINVAL
 	}
 
+	call := fs.callTool
+	if parts[3] == ".nocache" {
+		call = fs.callToolNoCache
+	}
+	if errno := call(callPath, serverName, toolName, args); errno != 0 {
+		return errno
+	}
+	return len(buff)
+}
+
+// callTool runs the tool and caches the result under callPath, which is
+// what .result reads from. arguments are checked against the input schema
+// first so a bad call never costs a round trip. tools with a cache ttl in
+// the server config are answered from the disk cache when possible.
+func (fs *CgoFS) callTool(callPath, serverName, toolName string, args map[string]any) int {
+	return fs.invoke(callPath, serverName, toolName, args, true)
+}
+
+// callToolNoCache skips the cache lookup but still refreshes the entry.
+func (fs *CgoFS) callToolNoCache(callPath, serverName, toolName string, args map[string]any) int {
+	return fs.invoke(callPath, serverName, toolName, args, false)
+}
+
+func (fs *CgoFS) invoke(callPath, serverName, toolName string, args map[string]any, useCache bool) int {
+	started := time.Now()
+
+	if err := fs.checkCall(serverName, toolName, args); err != nil {
+		fs.audit(serverName, toolName, args, started, nil, false, err.Error())
+		fs.setResult(callPath, errorResult(err.Error()))
+		return -fuse.EACCES
+	}
+
+	srv, _ := fs.cfg.GetServer(serverName)
+
+	// only results of read-only tools are ever put in the cache, so a hit
+	// is answered without starting the server
+	if useCache && srv != nil && srv.CacheTTL(toolName) > 0 {
+		if res, ok := fs.cache.Get(serverName, toolName, args); ok {
+			if fs.history != nil {
+				fs.history.Record(serverName, toolName, args, result.JSON(res), started, time.Since(started), res.IsError)
+			}
+			fs.audit(serverName, toolName, args, started, res, true, "")
+			fs.setResult(callPath, res)
+			return 0
+		}
+	}
+
 	conn, err := fs.pool.GetConnection(context.Background(), serverName)
 	if err != nil {
+		res := errorResult(fs.redactor(serverName).String(err.Error()))
+		fs.audit(serverName, toolName, args, started, res, false, "")
+		fs.setResult(callPath, res)
 		return -fuse.EIO
 	}
 
-	result, err := conn.CallTool(context.Background(), toolName, args)
+	// only tools that promise not to change anything are memoized
+	var ttl time.Duration
+	if tool, ok := findTool(conn.GetTools(), toolName); ok {
+		if sch, err := schema.Parse(tool.InputSchema); err == nil {
+			if violations := sch.Validate(args); len(violations) > 0 {
+				msg := schema.FormatViolations(violations)
+				fs.audit(serverName, toolName, args, started, nil, false, msg)
+				fs.setResult(callPath, errorResult(msg))
+				return -fuse.EINVAL
+			}
+		}
+		if srv != nil && srv.ConfirmDestructive && tool.Destructive() && !fs.takeConfirm(callPath) {
+			msg := toolName + " is destructive: write yes to .confirm, then call again"
+			fs.audit(serverName, toolName, args, started, nil, false, msg)
+			fs.setResult(callPath, errorResult(msg))
+			return -fuse.EPERM
+		}
+		if srv != nil && tool.ReadOnly() {
+			ttl = srv.CacheTTL(toolName)
+		}
+	}
+
+	started = time.Now()
+	res, err := conn.CallTool(context.Background(), toolName, args)
 	if err != nil {
-		result = &mcp.ToolResult{
-			Content: []mcp.ContentBlock{{Type: "text", Text: err.Error()}},
-			IsError: true,
+		res = errorResult(err.Error())
+	}
+	res = fs.redactResult(serverName, res)
+	if err == nil && ttl > 0 {
+		fs.cache.Put(serverName, toolName, args, res, ttl)
+	}
+
+	if fs.history != nil {
+		fs.history.Record(serverName, toolName, args, result.JSON(res), started, time.Since(started), res.IsError)
+	}
+	fs.audit(serverName, toolName, args, started, res, false, "")
+
+	fs.setResult(callPath, res)
+	return 0
+}
+
+// audit records a call along with the process that made it. it has to run
+// on the fuse request's goroutine for Getcontext to mean anything.
+func (fs *CgoFS) audit(serverName, toolName string, args map[string]any, started time.Time, res *mcp.ToolResult, cached bool, denied string) {
+	uid, _, pid := fuse.Getcontext()
+	fs.auditLog.Record(audit.Entry{
+		Time:       started,
+		Server:     serverName,
+		Tool:       toolName,
+		ArgsHash:   audit.HashArgs(args),
+		DurationMS: time.Since(started).Milliseconds(),
+		IsError:    res == nil || res.IsError,
+		Cached:     cached,
+		Denied:     fs.redactor(serverName).String(denied),
+		PID:        pid,
+		UID:        uid,
+	})
+}
+
+// redactor masks the server's stored auth values, whatever its secret
+// references resolved to, and anything shaped like a token. it's rebuilt on
+// use so `mcpfs auth` takes effect immediately.
+func (fs *CgoFS) redactor(serverName string) *redact.Redactor {
+	secrets := fs.pool.Secrets(serverName)
+	if auth, err := config.LoadAuth(fs.cfg.Dir(), serverName); err == nil && auth != nil {
+		for _, value := range auth.Data {
+			if !config.IsSecretRef(value) {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	return redact.New(secrets...)
+}
+
+// redactResult scrubs a result before it is shown, cached or kept in history.
+// binary data is left alone.
+func (fs *CgoFS) redactResult(serverName string, res *mcp.ToolResult) *mcp.ToolResult {
+	r := fs.redactor(serverName)
+
+	out := *res
+	out.Content = make([]mcp.ContentBlock, len(res.Content))
+	for i, block := range res.Content {
+		block.Text = r.String(block.Text)
+		block.Resource = r.Bytes(block.Resource)
+		out.Content[i] = block
+	}
+	out.StructuredContent = r.Bytes(res.StructuredContent)
+	out.Meta = r.Bytes(res.Meta)
+	out.Raw = r.Bytes(res.Raw)
+	return &out
+}
+
+// checkCall applies the server's allow/deny lists and argument rules.
+func (fs *CgoFS) checkCall(serverName, toolName string, args map[string]any) error {
+	srv, ok := fs.cfg.GetServer(serverName)
+	if !ok {
+		return nil
+	}
+	return srv.CheckCall(toolName, args)
+}
+
+// visibleTools drops tools the policy denies so they never show up.
+func (fs *CgoFS) visibleTools(serverName string, tools []mcp.Tool) []mcp.Tool {
+	srv, ok := fs.cfg.GetServer(serverName)
+	if !ok {
+		return tools
+	}
+	visible := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if srv.ToolAllowed(tool.Name) == nil {
+			visible = append(visible, tool)
 		}
 	}
+	return visible
+}
+
+// inDeniedTool reports whether parts point inside the directory of a tool
+// the policy hides, so none of its files can be reached by path either.
+func (fs *CgoFS) inDeniedTool(parts []string) bool {
+	if len(parts) < 4 || parts[0] == ".config" || parts[2] == ".elicit" {
+		return false
+	}
+	srv, ok := fs.cfg.GetServer(parts[0] + "/" + parts[1])
+	return ok && srv.ToolAllowed(parts[2]) != nil
+}
+
+// writeConfirm arms the next call of a destructive tool. anything but
+// yes/y disarms it again.
+func (fs *CgoFS) writeConfirm(toolPath string, buff []byte) int {
+	answer := strings.ToLower(strings.TrimSpace(string(buff)))
+
+	fs.mu.Lock()
+	if answer == "yes" || answer == "y" {
+		fs.confirmed[toolPath] = true
+	} else {
+		delete(fs.confirmed, toolPath)
+	}
+	fs.mu.Unlock()
+
+	return len(buff)
+}
+
+// takeConfirm consumes a confirmation so it covers exactly one call.
+func (fs *CgoFS) takeConfirm(callPath string) bool {
+	toolPath := strings.TrimSuffix(callPath, "/.call")
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	ok := fs.confirmed[toolPath]
+	delete(fs.confirmed, toolPath)
+	return ok
+}
+
+func (fs *CgoFS) setResult(callPath string, res *mcp.ToolResult) {
+	fs.mu.Lock()
+	fs.results[callPath] = res
+	fs.mu.Unlock()
+}
+
+// writeRoots replaces the server's roots with one directory per line and
+// lets a running server know they changed.
+func (fs *CgoFS) writeRoots(serverName string, buff []byte) int {
+	srv, ok := fs.cfg.GetServer(serverName)
+	if !ok {
+		return -fuse.ENOENT
+	}
+
+	var roots []string
+	for _, line := range strings.Split(string(buff), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			roots = append(roots, line)
+		}
+	}
+
+	updated := *srv
+	updated.Roots = roots
+	saved := fs.cfg.Saved()
+	saved[serverName] = &updated
+	if err := fs.cfg.SaveServers(saved); err != nil {
+		return -fuse.EIO
+	}
+	fs.cfg.SetServer(serverName, &updated)
+	fs.pool.NotifyRootsChanged(context.Background(), serverName)
+
+	return len(buff)
+}
+
+// writeComplete takes {"argument":"repo","value":"caff"} and stores the
+// suggestions for the next read. enum values from the input schema are used
+// when present, otherwise the server is asked via completion/complete if it
+// offers completions. failures are shown in .complete instead.
+func (fs *CgoFS) writeComplete(path, serverName, toolName string, buff []byte) int {
+	var req struct {
+		Argument string `json:"argument"`
+		Value    string `json:"value"`
+	}
+	if err := json.Unmarshal(buff, &req); err != nil || req.Argument == "" {
+		fs.setCompletions(path, nil, `expected {"argument": "...", "value": "..."}`)
+		return -fuse.EINVAL
+	}
+
+	conn, err := fs.pool.GetConnection(context.Background(), serverName)
+	if err != nil {
+		fs.setCompletions(path, nil, err.Error())
+		return -fuse.EIO
+	}
+	tool, ok := findTool(conn.GetTools(), toolName)
+	if !ok {
+		return -fuse.ENOENT
+	}
+
+	var values []string
+	if sch, err := schema.Parse(tool.InputSchema); err == nil {
+		values = sch.EnumValues(req.Argument, req.Value)
+	}
+	if len(values) > 0 {
+		fs.setCompletions(path, values, "")
+		return len(buff)
+	}
+	if conn.Capabilities().Completions == nil {
+		fs.setCompletions(path, nil, serverName+" doesn't offer completions and "+req.Argument+" has no enum")
+		return len(buff)
+	}
+
+	completion, err := conn.Complete(context.Background(),
+		mcp.CompletionRef{Type: mcp.RefTool, Name: toolName},
+		mcp.CompletionArgument{Name: req.Argument, Value: req.Value})
+	if err != nil {
+		fs.setCompletions(path, nil, fs.redactor(serverName).String(err.Error()))
+		return len(buff)
+	}
+	fs.setCompletions(path, completion.Values, "")
+	return len(buff)
+}
 
+func (fs *CgoFS) setCompletions(path string, values []string, errMsg string) {
 	fs.mu.Lock()
-	fs.results[path] = result
+	fs.completions[path] = values
+	if errMsg != "" {
+		fs.completeErr[path] = errMsg
+	} else {
+		delete(fs.completeErr, path)
+	}
+	fs.mu.Unlock()
+}
+
+// replay re-runs a call from history/ with the same arguments. the new call
+// gets its own history entry.
+func (fs *CgoFS) replay(toolPath, serverName, toolName string, buff []byte) int {
+	id := strings.TrimSpace(string(buff))
+	args, err := fs.history.Args(serverName, toolName, id)
+	if err != nil {
+		return -fuse.ENOENT
+	}
+	return fs.callTool(toolPath+"/.call", serverName, toolName, args)
+}
+
+// writeQuery sets the filter behind .result.query. it stays in place for
+// later calls until replaced; an empty write clears it. a filter that doesn't
+// parse is rejected and the reason left in .query.error.
+func (fs *CgoFS) writeQuery(toolPath string, buff []byte) int {
+	expr := strings.TrimSpace(string(buff))
+	if expr == "" {
+		fs.mu.Lock()
+		delete(fs.queries, toolPath)
+		delete(fs.queryErrs, toolPath)
+		fs.mu.Unlock()
+		return len(buff)
+	}
+
+	q, err := result.ParseQuery(expr)
+	if err != nil {
+		fs.mu.Lock()
+		fs.queryErrs[toolPath] = err.Error()
+		fs.mu.Unlock()
+		return -fuse.EINVAL
+	}
+
+	fs.mu.Lock()
+	fs.queries[toolPath] = q
+	delete(fs.queryErrs, toolPath)
 	fs.mu.Unlock()
 
 	return len(buff)
 }
 
 func (fs *CgoFS) Truncate(path string, size int64, fh uint64) int {
+	parts := splitPath(path)
+	if fs.inDeniedTool(parts) {
+		return -fuse.ENOENT
+	}
+	if isServersFile(parts) {
+		return fs.truncateEdit(path, fh, fs.serversContent, size)
+	}
+	if isAuthFile(parts) {
+		return fs.truncateEdit(path, fh, noContent, size)
+	}
+	if isServerConfigFile(parts) {
+		serverName := parts[0] + "/" + parts[1]
+		return fs.truncateEdit(path, fh, func() []byte { return fs.serverConfigContent(serverName) }, size)
+	}
+	if len(parts) == 5 && parts[3] == "args" {
+		return fs.truncateArg(toolPath(parts), parts[4], size)
+	}
 	return 0
 }
 
+func (fs *CgoFS) Create(path string, flags int, mode uint32) (int, uint64) {
+	parts := splitPath(path)
+	if fs.inDeniedTool(parts) {
+		return -fuse.ENOENT, 0
+	}
+	if len(parts) == 5 && parts[3] == "args" && parts[2] != ".elicit" {
+		return fs.truncateArg(toolPath(parts), parts[4], 0), 0
+	}
+	return -fuse.EACCES, 0
+}
+
+func (fs *CgoFS) Unlink(path string) int {
+	parts := splitPath(path)
+	if fs.inDeniedTool(parts) {
+		return -fuse.ENOENT
+	}
+	if len(parts) == 5 && parts[3] == "args" {
+		return fs.unlinkArg(toolPath(parts), parts[4])
+	}
+	return -fuse.EACCES
+}
+
 func (fs *CgoFS) getFileContent(path string) []byte {
 	parts := splitPath(path)
+	if fs.inDeniedTool(parts) {
+		return nil
+	}
 
 	switch len(parts) {
-	case 2: // .config/servers.json
-		if parts[0] == ".config" && parts[1] == "servers.json" {
-			data, _ := config.MarshalServers(fs.cfg.Servers)
-			return data
+	case 1: // .audit
+		if parts[0] == ".audit" {
+			return fs.auditLog.Recent(auditRecent)
+		}
+
+	case 2: // .config/servers.json and its .error
+		if isServersFile(parts) {
+			return fs.serversContent()
+		}
+		if parts[0] == ".config" && parts[1] == serversErrorFile {
+			return fs.serversError()
 		}
 
 	case 3: // .status or .schema
 		serverName := parts[0] + "/" + parts[1]
 		fileName := parts[2]
 
+		if fileName == serverConfigFile {
+			return fs.serverConfigContent(serverName)
+		}
+
+		if fileName == authFile {
+			return fs.authKeys(serverName)
+		}
+
 		if fileName == ".status" {
+			configErr := ""
+			if msg := fs.configError(serverName); msg != "" {
+				configErr = "config error: " + msg + "\n"
+			}
+			if fs.isPendingServer(serverName) {
+				return []byte("pending: write " + serverConfigFile + " to finish adding this server\n" + configErr)
+			}
 			status := fs.pool.GetStatus()
 			info, ok := status[serverName]
 			if !ok {
-				return []byte("disconnected\n")
+				return []byte("disconnected\n" + configErr)
+			}
+			out := "status: " + info.Status + "\n" + configErr
+			if info.Error != "" {
+				out += "error: " + fs.redactor(serverName).String(info.Error) + "\n"
+			}
+			if srv, ok := fs.cfg.GetServer(serverName); ok && len(srv.Cache) > 0 {
+				st := fs.cache.Stats(serverName)
+				out += fmt.Sprintf("cache: %d hits, %d misses\n", st.Hits, st.Misses)
+			}
+			return []byte(out)
+		}
+
+		if fileName == ".log" {
+			return fs.redactor(serverName).Bytes(fs.pool.Log(serverName))
+		}
+
+		if fileName == ".roots" {
+			srv, ok := fs.cfg.GetServer(serverName)
+			if !ok || len(srv.Roots) == 0 {
+				return []byte{}
 			}
-			return []byte("status: " + info.Status + "\n")
+			return []byte(strings.Join(srv.Roots, "\n") + "\n")
 		}
 
 		if fileName == ".schema" {
@@ -253,7 +987,7 @@ func (fs *CgoFS) getFileContent(path string) []byte {
 			if err != nil {
 				return []byte("error: " + err.Error() + "\n")
 			}
-			tools := conn.GetTools()
+			tools := fs.visibleTools(serverName, conn.GetTools())
 			data, _ := json.MarshalIndent(tools, "", "  ")
 			return append(data, '\n')
 		}
@@ -276,38 +1010,168 @@ func (fs *CgoFS) getFileContent(path string) []byte {
 			}
 		}
 
+		// reading .call is a call without arguments, with the same checks
 		if fileName == ".call" {
-			conn, err := fs.pool.GetConnection(context.Background(), serverName)
-			if err != nil {
-				return []byte("error: " + err.Error() + "\n")
+			fs.callTool(path, serverName, toolName, nil)
+			return result.Text(fs.lastResult(toolPath(parts)))
+		}
+
+		if fileName == ".run" || fileName == ".replay" || fileName == ".nocache" {
+			return []byte{}
+		}
+
+		if fileName == ".confirm" {
+			fs.mu.RLock()
+			armed := fs.confirmed[toolPath(parts)]
+			fs.mu.RUnlock()
+			if armed {
+				return []byte("yes\n")
 			}
-			result, err := conn.CallTool(context.Background(), toolName, nil)
-			if err != nil {
-				return []byte("error: " + err.Error() + "\n")
+			return []byte{}
+		}
+
+		if fileName == ".query" {
+			fs.mu.RLock()
+			q := fs.queries[toolPath(parts)]
+			fs.mu.RUnlock()
+			if q == nil {
+				return []byte{}
 			}
-			fs.mu.Lock()
-			fs.results[path] = result
-			fs.mu.Unlock()
-			return formatToolResult(result)
+			return []byte(q.String() + "\n")
 		}
 
-		if fileName == ".result" {
-			resultPath := strings.TrimSuffix(path, ".result") + ".call"
+		if fileName == ".query.error" {
 			fs.mu.RLock()
-			result := fs.results[resultPath]
+			msg := fs.queryErrs[toolPath(parts)]
 			fs.mu.RUnlock()
-			if result == nil {
+			if msg == "" {
+				return []byte{}
+			}
+			return []byte(msg + "\n")
+		}
+
+		if fileName == ".complete" {
+			fs.mu.RLock()
+			values := fs.completions[path]
+			errMsg := fs.completeErr[path]
+			fs.mu.RUnlock()
+			if errMsg != "" {
+				return []byte("error: " + errMsg + "\n")
+			}
+			if len(values) == 0 {
+				return []byte{}
+			}
+			return []byte(strings.Join(values, "\n") + "\n")
+		}
+
+		if view := fs.resultView(toolPath(parts), fileName); view != nil {
+			res := fs.lastResult(toolPath(parts))
+			if res == nil {
 				return []byte("(no result yet)\n")
 			}
-			return formatToolResult(result)
+			return view(res)
+		}
+
+	case 6: // history/<id>/ files
+		if parts[3] != "history" || fs.history == nil {
+			return nil
+		}
+		data, err := fs.history.ReadFile(parts[0]+"/"+parts[1], parts[2], parts[4], parts[5])
+		if err != nil {
+			return nil
+		}
+		return data
+
+	case 5: // .elicit/<id>/ files, args/<property>, or a page
+		if parts[3] == ".result.pages" && parts[2] != ".elicit" {
+			return fs.resultPage(parts)
+		}
+		if parts[3] == "args" && parts[2] != ".elicit" {
+			if value := fs.argValue(toolPath(parts), parts[4]); value != nil {
+				return value
+			}
+			return []byte{}
+		}
+		if parts[2] != ".elicit" {
+			return nil
+		}
+		p := fs.elicits.get(parts[0]+"/"+parts[1], parts[3])
+		if p == nil {
+			return nil
+		}
+		switch parts[4] {
+		case "message":
+			return []byte(p.req.Message + "\n")
+		case "schema":
+			data, _ := json.MarshalIndent(p.req.RequestedSchema, "", "  ")
+			return append(data, '\n')
+		case "response":
+			return []byte{}
+		}
+	}
+
+	return nil
+}
+
+func (fs *CgoFS) resultView(toolPath, name string) func(*mcp.ToolResult) []byte {
+	switch name {
+	case ".result":
+		return result.Text
+	case ".result.json":
+		return result.JSON
+	case ".result.ndjson":
+		return result.NDJSON
+	case ".result.md":
+		return result.Markdown
+	case ".result.head":
+		return func(r *mcp.ToolResult) []byte {
+			return result.Head(r, fs.opts.HeadLines)
+		}
+	case ".result.summary":
+		return func(r *mcp.ToolResult) []byte {
+			return result.Summary(r, fs.opts.PageSize)
+		}
+	case ".result.query":
+		return func(r *mcp.ToolResult) []byte {
+			fs.mu.RLock()
+			q := fs.queries[toolPath]
+			fs.mu.RUnlock()
+			if q == nil {
+				return []byte("(no query set, write one to .query)\n")
+			}
+			data, err := result.Project(r, q)
+			if err != nil {
+				return []byte("error: " + err.Error() + "\n")
+			}
+			return data
 		}
 	}
+	return nil
+}
 
+func (fs *CgoFS) lastResult(toolPath string) *mcp.ToolResult {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.results[toolPath+"/.call"]
+}
+
+// resultPage returns .result.pages/<n>, or nil if there is no such page.
+func (fs *CgoFS) resultPage(parts []string) []byte {
+	res := fs.lastResult(toolPath(parts))
+	if res == nil {
+		return nil
+	}
+	pages := result.Pages(res, fs.opts.PageSize)
+	for i, page := range pages {
+		if result.PageName(i, len(pages)) == parts[4] {
+			return page
+		}
+	}
 	return nil
 }
 
 func (fs *CgoFS) hasScope(name string) bool {
-	for serverName := range fs.cfg.Servers {
+	for _, serverName := range append(fs.cfg.Names(), fs.pendingNames()...) {
 		scope, _ := config.ParseServerName(serverName)
 		if scope == name {
 			return true
@@ -316,6 +1180,27 @@ func (fs *CgoFS) hasScope(name string) bool {
 	return false
 }
 
+func errorResult(text string) *mcp.ToolResult {
+	return &mcp.ToolResult{
+		Content: []mcp.ContentBlock{{Type: "text", Text: text}},
+		IsError: true,
+	}
+}
+
+func findTool(tools []mcp.Tool, name string) (*mcp.Tool, bool) {
+	for i := range tools {
+		if tools[i].Name == name {
+			return &tools[i], true
+		}
+	}
+	return nil, false
+}
+
+// toolPath is the tool directory a path points into.
+func toolPath(parts []string) string {
+	return "/" + strings.Join(parts[:3], "/")
+}
+
 func splitPath(path string) []string {
 	path = strings.Trim(path, "/")
 	if path == "" {
@@ -323,16 +1208,3 @@ func splitPath(path string) []string {
 	}
 	return strings.Split(path, "/")
 }
-
-func formatToolResult(result *mcp.ToolResult) []byte {
-	for _, block := range result.Content {
-		if block.Type == "text" {
-			if result.IsError {
-				return []byte("error: " + block.Text + "\n")
-			}
-			return []byte(block.Text + "\n")
-		}
-	}
-	data, _ := json.MarshalIndent(result.Content, "", "  ")
-	return append(data, '\n')
-}
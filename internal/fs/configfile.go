@@ -0,0 +1,378 @@
+package fs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/winfsp/cgofuse/fuse"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+)
+
+// .config/servers.json and each server's .config.json are editable in
+// place. writes go to a buffer per open handle that is parsed when the file
+// is closed, so close(2) reports a bad edit; it also leaves the running
+// config alone and explains itself in .config/servers.json.error or the
+// server's .status.
+//
+// mkdir @scope/name adds a pending server that only has .config.json until
+// a valid config is written; rmdir removes a server.
+
+const (
+	serversFile      = "servers.json"
+	serversErrorFile = "servers.json.error"
+	serverConfigFile = ".config.json"
+)
+
+func isServersFile(parts []string) bool {
+	return len(parts) == 2 && parts[0] == ".config" && parts[1] == serversFile
+}
+
+func isServerConfigFile(parts []string) bool {
+	return len(parts) == 3 && parts[0] != ".config" && parts[2] == serverConfigFile
+}
+
+// serversContent is the live config, without the project's servers.
+func (fs *CgoFS) serversContent() []byte {
+	data, _ := config.MarshalServers(fs.cfg.Saved())
+	return append(data, '\n')
+}
+
+func (fs *CgoFS) serverConfigContent(serverName string) []byte {
+	srv, ok := fs.cfg.GetServer(serverName)
+	if !ok {
+		return []byte{}
+	}
+	data, _ := json.MarshalIndent(srv, "", "  ")
+	return append(data, '\n')
+}
+
+func (fs *CgoFS) serversError() []byte {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	if fs.configErrs[""] == "" {
+		return []byte{}
+	}
+	return []byte(fs.configErrs[""] + "\n")
+}
+
+// edit is what has been written to an editable file through one open
+// handle, so two writers never mix their bytes. data stays nil until the
+// first write or truncate.
+type edit struct {
+	path string
+	data []byte
+}
+
+func isEditable(parts []string) bool {
+	return isServersFile(parts) || isServerConfigFile(parts) || isAuthFile(parts)
+}
+
+// openEdit hands out a file handle for an editable file.
+func (fs *CgoFS) openEdit(path string) uint64 {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.nextFh++
+	fs.edits[fs.nextFh] = &edit{path: path}
+	return fs.nextFh
+}
+
+func (fs *CgoFS) closeEdit(fh uint64) {
+	fs.mu.Lock()
+	delete(fs.edits, fh)
+	fs.mu.Unlock()
+}
+
+func (fs *CgoFS) hasEdit(path string, fh uint64) bool {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	e := fs.edits[fh]
+	return e != nil && e.path == path
+}
+
+// pendingEdit is what has been written through fh and not committed yet.
+func (fs *CgoFS) pendingEdit(fh uint64) []byte {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	if e := fs.edits[fh]; e != nil {
+		return e.data
+	}
+	return nil
+}
+
+// editContent is what a read through fh sees: its own pending edit, if it
+// has one, else current.
+func (fs *CgoFS) editContent(fh uint64, current func() []byte) []byte {
+	if data := fs.pendingEdit(fh); data != nil {
+		return data
+	}
+	return current()
+}
+
+// startEdit seeds the buffer with the current content so partial writes at
+// an offset behave like they would on a regular file.
+func (fs *CgoFS) startEdit(fh uint64, current func() []byte) *edit {
+	fs.mu.RLock()
+	e := fs.edits[fh]
+	started := e != nil && e.data != nil
+	fs.mu.RUnlock()
+	if e == nil || started {
+		return e
+	}
+	data := current()
+
+	fs.mu.Lock()
+	if e.data == nil {
+		e.data = data
+	}
+	fs.mu.Unlock()
+	return e
+}
+
+func (fs *CgoFS) writeEdit(fh uint64, current func() []byte, buff []byte, ofst int64) int {
+	e := fs.startEdit(fh, current)
+	if e == nil {
+		return -fuse.EBADF
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data := e.data
+	if end := ofst + int64(len(buff)); end > int64(len(data)) {
+		data = append(data, make([]byte, end-int64(len(data)))...)
+	}
+	copy(data[ofst:], buff)
+	e.data = data
+
+	return len(buff)
+}
+
+// truncateEdit resizes the edit behind fh. a truncate by path, without an
+// open handle, is committed right away.
+func (fs *CgoFS) truncateEdit(path string, fh uint64, current func() []byte, size int64) int {
+	if !fs.hasEdit(path, fh) {
+		fh = fs.openEdit(path)
+		defer fs.closeEdit(fh)
+		if errno := fs.truncateEdit(path, fh, current, size); errno != 0 {
+			return errno
+		}
+		return fs.commitEdit(path, fh)
+	}
+
+	e := fs.startEdit(fh, current)
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data := e.data
+	if size <= int64(len(data)) {
+		data = data[:size]
+	} else {
+		data = append(data, make([]byte, size-int64(len(data)))...)
+	}
+	e.data = data
+
+	return 0
+}
+
+// takeEdit returns what was written through fh and starts it over, so a
+// second flush of the same handle doesn't apply it again.
+func (fs *CgoFS) takeEdit(fh uint64) []byte {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	e := fs.edits[fh]
+	if e == nil {
+		return nil
+	}
+	data := e.data
+	e.data = nil
+	return data
+}
+
+// commitEdit applies what was written through fh. it runs on flush, so the
+// error reaches close(2).
+func (fs *CgoFS) commitEdit(path string, fh uint64) int {
+	parts := splitPath(path)
+	switch {
+	case isServersFile(parts):
+		return fs.commitServers(fh)
+	case isServerConfigFile(parts):
+		return fs.commitServerConfig(fh, parts[0]+"/"+parts[1])
+	case isAuthFile(parts):
+		return fs.commitAuth(fh, parts[0]+"/"+parts[1])
+	}
+	return 0
+}
+
+// commitServers validates the buffered servers.json, saves it and only then
+// applies it to the running mount.
+func (fs *CgoFS) commitServers(fh uint64) int {
+	data := fs.takeEdit(fh)
+	if data == nil {
+		return 0
+	}
+
+	servers, err := config.ParseServers(data)
+	if err != nil {
+		fs.setConfigError("", err.Error())
+		return -fuse.EINVAL
+	}
+
+	if err := fs.cfg.SaveServers(servers); err != nil {
+		fs.setConfigError("", err.Error())
+		return -fuse.EIO
+	}
+	fs.applyChanges(fs.cfg.Replace(servers))
+	fs.setConfigError("", "")
+
+	return 0
+}
+
+// commitServerConfig does the same for one server's .config.json. a pending
+// server becomes a real one here.
+func (fs *CgoFS) commitServerConfig(fh uint64, serverName string) int {
+	data := fs.takeEdit(fh)
+	if data == nil {
+		return 0
+	}
+
+	wrapped := append([]byte(fmt.Sprintf("{%q: ", serverName)), data...)
+	wrapped = append(wrapped, '}')
+	servers, err := config.ParseServers(wrapped)
+	if err != nil {
+		fs.setConfigError(serverName, strings.TrimPrefix(err.Error(), serverName+": "))
+		return -fuse.EINVAL
+	}
+
+	old, _ := fs.cfg.GetServer(serverName)
+	srv := servers[serverName]
+	saved := fs.cfg.Saved()
+	saved[serverName] = srv
+	if err := fs.cfg.SaveServers(saved); err != nil {
+		fs.setConfigError(serverName, err.Error())
+		return -fuse.EIO
+	}
+	fs.cfg.SetServer(serverName, srv)
+
+	fs.mu.Lock()
+	delete(fs.pending, serverName)
+	fs.mu.Unlock()
+
+	fs.applyChanges(config.Diff(
+		map[string]*config.ServerConfig{serverName: old},
+		map[string]*config.ServerConfig{serverName: srv},
+	))
+	fs.setConfigError(serverName, "")
+
+	return 0
+}
+
+func (fs *CgoFS) configError(serverName string) string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.configErrs[serverName]
+}
+
+// setConfigError records why an edit was rejected. "" is servers.json.
+func (fs *CgoFS) setConfigError(serverName, msg string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if msg == "" {
+		delete(fs.configErrs, serverName)
+	} else {
+		fs.configErrs[serverName] = msg
+	}
+}
+
+func (fs *CgoFS) isPendingServer(serverName string) bool {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return fs.pending[serverName]
+}
+
+// pendingNames lists mkdir'd servers and scopes in server name form.
+func (fs *CgoFS) pendingNames() []string {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	var names []string
+	for name := range fs.pending {
+		names = append(names, name)
+	}
+	for scope := range fs.pendingDirs {
+		names = append(names, scope)
+	}
+	return names
+}
+
+func (fs *CgoFS) Mkdir(path string, mode uint32) int {
+	parts := splitPath(path)
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "@") {
+		return -fuse.EACCES
+	}
+
+	switch len(parts) {
+	case 1:
+		if fs.hasScope(parts[0]) {
+			return -fuse.EEXIST
+		}
+		fs.mu.Lock()
+		fs.pendingDirs[parts[0]] = true
+		fs.mu.Unlock()
+		return 0
+
+	case 2:
+		serverName := parts[0] + "/" + parts[1]
+		if _, ok := fs.cfg.GetServer(serverName); ok || fs.isPendingServer(serverName) {
+			return -fuse.EEXIST
+		}
+		fs.mu.Lock()
+		fs.pending[serverName] = true
+		delete(fs.pendingDirs, parts[0])
+		fs.mu.Unlock()
+		return 0
+	}
+	return -fuse.EACCES
+}
+
+func (fs *CgoFS) Rmdir(path string) int {
+	parts := splitPath(path)
+
+	switch len(parts) {
+	case 1:
+		fs.mu.Lock()
+		pending := fs.pendingDirs[parts[0]]
+		delete(fs.pendingDirs, parts[0])
+		fs.mu.Unlock()
+		switch {
+		case pending:
+			return 0
+		case fs.hasScope(parts[0]):
+			return -fuse.ENOTEMPTY
+		}
+		return -fuse.ENOENT
+
+	case 2:
+		serverName := parts[0] + "/" + parts[1]
+		if fs.isPendingServer(serverName) {
+			fs.mu.Lock()
+			delete(fs.pending, serverName)
+			fs.mu.Unlock()
+			return 0
+		}
+		if _, ok := fs.cfg.GetServer(serverName); !ok {
+			return -fuse.ENOENT
+		}
+		saved := fs.cfg.Saved()
+		delete(saved, serverName)
+		if err := fs.cfg.SaveServers(saved); err != nil {
+			return -fuse.EIO
+		}
+		fs.cfg.RemoveServer(serverName)
+		fs.pool.CloseConnection(serverName)
+		fs.setConfigError(serverName, "")
+		return 0
+	}
+	return -fuse.EACCES
+}
@@ -0,0 +1,171 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+const elicitTimeout = 5 * time.Minute
+
+// pendingElicit is a question a server asked mid-call. it shows up as
+// .elicit/<id>/ until someone writes the response file or it times out.
+type pendingElicit struct {
+	req    *mcp.ElicitRequest
+	answer chan *mcp.ElicitResult
+}
+
+type elicitQueue struct {
+	mu      sync.Mutex
+	nextID  int
+	pending map[string]map[string]*pendingElicit // server -> id -> question
+	timeout time.Duration
+}
+
+func newElicitQueue(timeout time.Duration) *elicitQueue {
+	return &elicitQueue{
+		pending: make(map[string]map[string]*pendingElicit),
+		timeout: timeout,
+	}
+}
+
+// ask blocks the tool call until the question is answered through the
+// filesystem. an unanswered question is cancelled after the timeout.
+func (q *elicitQueue) ask(ctx context.Context, serverName string, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+	p := &pendingElicit{
+		req:    req,
+		answer: make(chan *mcp.ElicitResult, 1),
+	}
+
+	q.mu.Lock()
+	q.nextID++
+	id := strconv.Itoa(q.nextID)
+	if q.pending[serverName] == nil {
+		q.pending[serverName] = make(map[string]*pendingElicit)
+	}
+	q.pending[serverName][id] = p
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		delete(q.pending[serverName], id)
+		if len(q.pending[serverName]) == 0 {
+			delete(q.pending, serverName)
+		}
+		q.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(q.timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-p.answer:
+		return result, nil
+	case <-timer.C:
+		return &mcp.ElicitResult{Action: mcp.ElicitCancel}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *elicitQueue) ids(serverName string) []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var ids []string
+	for id := range q.pending[serverName] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.Atoi(ids[i])
+		b, _ := strconv.Atoi(ids[j])
+		return a < b
+	})
+	return ids
+}
+
+func (q *elicitQueue) get(serverName, id string) *pendingElicit {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pending[serverName][id]
+}
+
+func (q *elicitQueue) respond(serverName, id string, data []byte) error {
+	p := q.get(serverName, id)
+	if p == nil {
+		return fmt.Errorf("no pending question %s", id)
+	}
+
+	result, err := parseElicitResponse(p.req, data)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case p.answer <- result:
+		return nil
+	default:
+		return fmt.Errorf("question %s already answered", id)
+	}
+}
+
+// parseElicitResponse accepts a full {"action":...} result, a bare content
+// object, the words accept/decline/cancel, or plain text when the requested
+// schema has a single property.
+func parseElicitResponse(req *mcp.ElicitRequest, data []byte) (*mcp.ElicitResult, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty response")
+	}
+
+	if data[0] == '{' {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("parse response: %w", err)
+		}
+		if _, ok := obj["action"]; ok {
+			var result mcp.ElicitResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return nil, fmt.Errorf("parse response: %w", err)
+			}
+			return &result, nil
+		}
+		var content map[string]any
+		json.Unmarshal(data, &content)
+		return &mcp.ElicitResult{Action: mcp.ElicitAccept, Content: content}, nil
+	}
+
+	text := string(data)
+	switch strings.ToLower(text) {
+	case mcp.ElicitDecline, mcp.ElicitCancel:
+		return &mcp.ElicitResult{Action: strings.ToLower(text)}, nil
+	case mcp.ElicitAccept:
+		return &mcp.ElicitResult{Action: mcp.ElicitAccept}, nil
+	}
+
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	json.Unmarshal(req.RequestedSchema, &schema)
+	if len(schema.Properties) != 1 {
+		return nil, fmt.Errorf("response must be a json object for this question")
+	}
+	var name string
+	for name = range schema.Properties {
+	}
+
+	var value any = text
+	json.Unmarshal(data, &value) // numbers and booleans
+	return &mcp.ElicitResult{
+		Action:  mcp.ElicitAccept,
+		Content: map[string]any{name: value},
+	}, nil
+}
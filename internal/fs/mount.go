@@ -14,23 +14,50 @@ import (
 
 type MountOptions struct {
 	Mountpoint string
-	ConfigDir  string
+	ConfigDir  string          // already resolved to the profile's dir
+	Project    *config.Project // trusted ProjectFile merged over the config, nil for none
 	Foreground bool
+	HeadLines  int
+	PageSize   int
+	History    int // calls kept per tool, <0 disables
 }
 
 func Mount(opts MountOptions) error {
+	// report everything wrong at once rather than the first thing Load trips on
+	problems := config.ValidateDir(opts.ConfigDir)
+	if opts.Project != nil {
+		problems = append(problems, config.ValidateProject(opts.Project.Path, opts.ConfigDir)...)
+	}
+	for _, problem := range problems {
+		fmt.Fprintln(os.Stderr, problem)
+	}
+	if config.HasFatal(problems) {
+		return fmt.Errorf("invalid config, see mcpfs config validate")
+	}
+
 	cfg, err := config.Load(opts.ConfigDir)
 	if err != nil {
 		return fmt.Errorf("load config: %w", err)
 	}
+	if opts.Project != nil {
+		cfg.SetProject(opts.Project)
+		fmt.Printf("using project servers from %s\n", opts.Project.Path)
+	}
 
 	p := pool.New(pool.PoolConfig{
 		Config: cfg,
 	})
 
-	cgoFS := NewCgoFS(cfg, p)
+	cgoFS := NewCgoFS(cfg, p, Options{
+		HeadLines:    opts.HeadLines,
+		PageSize:     opts.PageSize,
+		HistoryLimit: opts.History,
+	})
 	host := fuse.NewFileSystemHost(cgoFS)
 
+	stopWatch := make(chan struct{})
+	go cgoFS.watchConfig(stopWatch)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
@@ -48,6 +75,7 @@ func Mount(opts MountOptions) error {
 
 	ok := host.Mount("", mountArgs)
 
+	close(stopWatch)
 	p.Close()
 
 	if !ok {
@@ -0,0 +1,74 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+)
+
+const reloadInterval = time.Second
+
+// watchConfig polls servers.json and applies edits
+// made by other processes (mcpfs add, an editor) until stop is closed.
+// polling keeps it portable across macOS and linux without extra
+// dependencies.
+func (fs *CgoFS) watchConfig(stop <-chan struct{}) {
+	serversPath := filepath.Join(fs.cfg.Dir(), "servers.json")
+	last := fileStamp(serversPath)
+
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			current := fileStamp(serversPath)
+			if current == last {
+				continue
+			}
+			last = current
+			if err := fs.reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "reload config: %v\n", err)
+			}
+		}
+	}
+}
+
+// reload swaps in the config from disk. servers that were removed or changed
+// lose their connection and reconnect with the new settings on next access;
+// a roots-only change is announced to the running server instead.
+func (fs *CgoFS) reload() error {
+	changes, err := fs.cfg.Reload()
+	if err != nil {
+		return err
+	}
+	fs.applyChanges(changes)
+	return nil
+}
+
+func (fs *CgoFS) applyChanges(changes []config.Change) {
+	for _, ch := range changes {
+		switch {
+		case ch.Old == nil:
+			// added, connects lazily
+		case ch.RootsOnly():
+			fs.pool.NotifyRootsChanged(context.Background(), ch.Name)
+		default:
+			fs.pool.CloseConnection(ch.Name)
+		}
+	}
+}
+
+func fileStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
@@ -0,0 +1,166 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+)
+
+const (
+	ArgsFile   = "args.json"
+	ResultFile = "result.json"
+	MetaFile   = "meta.json"
+)
+
+// Store keeps every tool call on disk as
+// <dir>/<server>/<tool>/<id>/{args.json,result.json,meta.json}, trimming
+// each tool to the newest limit entries.
+type Store struct {
+	dir   string
+	limit int
+	mu    sync.Mutex
+}
+
+type Entry struct {
+	ID         string    `json:"id"`
+	Server     string    `json:"server"`
+	Tool       string    `json:"tool"`
+	Time       time.Time `json:"time"`
+	DurationMS int64     `json:"durationMs"`
+	IsError    bool      `json:"isError"`
+}
+
+func New(dir string, limit int) *Store {
+	return &Store{dir: dir, limit: limit}
+}
+
+func (s *Store) toolDir(server, tool string) string {
+	return filepath.Join(s.dir, config.SafeServerName(server), tool)
+}
+
+// Record writes a call. result is the raw result envelope.
+func (s *Store) Record(server, tool string, args map[string]any, result []byte, started time.Time, duration time.Duration, isError bool) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if args == nil {
+		args = map[string]any{}
+	}
+	argsData, err := json.MarshalIndent(args, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal args: %w", err)
+	}
+
+	toolDir := s.toolDir(server, tool)
+	id := started.UTC().Format("20060102-150405.000")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(filepath.Join(toolDir, id)); os.IsNotExist(err) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", started.UTC().Format("20060102-150405.000"), n)
+	}
+
+	entryDir := filepath.Join(toolDir, id)
+	if err := os.MkdirAll(entryDir, 0700); err != nil {
+		return nil, fmt.Errorf("create history entry: %w", err)
+	}
+
+	entry := &Entry{
+		ID:         id,
+		Server:     server,
+		Tool:       tool,
+		Time:       started,
+		DurationMS: duration.Milliseconds(),
+		IsError:    isError,
+	}
+	meta, _ := json.MarshalIndent(entry, "", "  ")
+
+	files := map[string][]byte{
+		ArgsFile:   append(argsData, '\n'),
+		ResultFile: result,
+		MetaFile:   append(meta, '\n'),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(entryDir, name), data, 0600); err != nil {
+			return nil, fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+
+	s.prune(toolDir)
+	return entry, nil
+}
+
+func (s *Store) prune(toolDir string) {
+	if s.limit <= 0 {
+		return
+	}
+	ids := listDirs(toolDir)
+	for len(ids) > s.limit {
+		os.RemoveAll(filepath.Join(toolDir, ids[0]))
+		ids = ids[1:]
+	}
+}
+
+// List returns entry ids for a tool, oldest first.
+func (s *Store) List(server, tool string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return listDirs(s.toolDir(server, tool))
+}
+
+func (s *Store) Get(server, tool, id string) (*Entry, error) {
+	data, err := s.ReadFile(server, tool, id, MetaFile)
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("parse history entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Args returns the arguments of a recorded call, ready to replay.
+func (s *Store) Args(server, tool, id string) (map[string]any, error) {
+	data, err := s.ReadFile(server, tool, id, ArgsFile)
+	if err != nil {
+		return nil, err
+	}
+	var args map[string]any
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, fmt.Errorf("parse history args: %w", err)
+	}
+	return args, nil
+}
+
+func (s *Store) ReadFile(server, tool, id, name string) ([]byte, error) {
+	if !validName(id) || !validName(name) {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(filepath.Join(s.toolDir(server, tool), id, name))
+}
+
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && filepath.Base(name) == name
+}
+
+func listDirs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() {
+			ids = append(ids, e.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
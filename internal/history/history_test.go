@@ -0,0 +1,90 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	store := New(t.TempDir(), 10)
+	started := time.Date(2026, 10, 18, 15, 30, 12, 0, time.UTC)
+
+	entry, err := store.Record("@github/mcp", "search", map[string]any{"query": "mcpfs"},
+		[]byte(`{"content":[]}`), started, 1500*time.Millisecond, true)
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if entry.ID != "20261018-153012.000" {
+		t.Errorf("unexpected id: %s", entry.ID)
+	}
+
+	ids := store.List("@github/mcp", "search")
+	if len(ids) != 1 || ids[0] != entry.ID {
+		t.Fatalf("unexpected ids: %v", ids)
+	}
+
+	got, err := store.Get("@github/mcp", "search", entry.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.DurationMS != 1500 || !got.IsError || got.Tool != "search" {
+		t.Errorf("unexpected entry: %+v", got)
+	}
+
+	args, err := store.Args("@github/mcp", "search", entry.ID)
+	if err != nil {
+		t.Fatalf("args: %v", err)
+	}
+	if args["query"] != "mcpfs" {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	result, err := store.ReadFile("@github/mcp", "search", entry.ID, ResultFile)
+	if err != nil || string(result) != `{"content":[]}` {
+		t.Errorf("unexpected result: %s, %v", result, err)
+	}
+}
+
+func TestRecordSameInstant(t *testing.T) {
+	store := New(t.TempDir(), 10)
+	now := time.Now()
+
+	a, _ := store.Record("@a/b", "tool", nil, []byte("{}"), now, 0, false)
+	b, _ := store.Record("@a/b", "tool", nil, []byte("{}"), now, 0, false)
+	if a.ID == b.ID {
+		t.Errorf("expected distinct ids, got %s twice", a.ID)
+	}
+	if len(store.List("@a/b", "tool")) != 2 {
+		t.Error("expected both entries kept")
+	}
+}
+
+func TestRetention(t *testing.T) {
+	store := New(t.TempDir(), 3)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		store.Record("@a/b", "tool", map[string]any{"i": i}, []byte("{}"), start.Add(time.Duration(i)*time.Second), 0, false)
+	}
+
+	ids := store.List("@a/b", "tool")
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 entries, got %v", ids)
+	}
+	args, _ := store.Args("@a/b", "tool", ids[0])
+	if args["i"] != 2.0 {
+		t.Errorf("expected oldest kept entry to be call 2, got %v", args["i"])
+	}
+}
+
+func TestReadFileRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	store := New(dir, 10)
+	os.WriteFile(filepath.Join(dir, "secret"), []byte("x"), 0600)
+
+	if _, err := store.ReadFile("@a/b", "tool", "..", "secret"); err == nil {
+		t.Error("expected traversal to be rejected")
+	}
+}
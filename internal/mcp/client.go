@@ -11,25 +11,132 @@ type Client interface {
 	Initialize(ctx context.Context) error
 	ListTools(ctx context.Context) ([]Tool, error)
 	CallTool(ctx context.Context, name string, args map[string]any) (*ToolResult, error)
+	Complete(ctx context.Context, ref CompletionRef, arg CompletionArgument) (*Completion, error)
+	Notify(ctx context.Context, method string, params any) error
+	// Capabilities is what the server announced in its initialize result.
+	Capabilities() ServerCapabilities
 	Close() error
 }
 
 type Tool struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description,omitempty"`
-	InputSchema json.RawMessage `json:"inputSchema,omitempty"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	InputSchema json.RawMessage  `json:"inputSchema,omitempty"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"`
+}
+
+// ToolAnnotations are hints a server gives about a tool's behavior. they are
+// untrusted and every field is optional, hence the pointers.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// ReadOnly reports whether the tool says it doesn't modify anything.
+func (t *Tool) ReadOnly() bool {
+	return t.Annotations != nil && t.Annotations.ReadOnlyHint != nil && *t.Annotations.ReadOnlyHint
+}
+
+// Destructive reports whether the tool may delete or overwrite things. per
+// the spec destructiveHint defaults to true for annotated tools that aren't
+// read-only; tools without any annotations are treated as unknown, not
+// destructive.
+func (t *Tool) Destructive() bool {
+	if t.Annotations == nil || t.ReadOnly() {
+		return false
+	}
+	if t.Annotations.DestructiveHint == nil {
+		return true
+	}
+	return *t.Annotations.DestructiveHint
 }
 
 type ToolResult struct {
-	Content []ContentBlock `json:"content"`
-	IsError bool           `json:"isError,omitempty"`
+	Content           []ContentBlock  `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
+	Meta              json.RawMessage `json:"_meta,omitempty"`
+
+	// Raw is the result exactly as the server sent it.
+	Raw json.RawMessage `json:"-"`
 }
 
 type ContentBlock struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	Data     string          `json:"data,omitempty"`
+	MimeType string          `json:"mimeType,omitempty"`
+	URI      string          `json:"uri,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+}
+
+// reference types for completion/complete. ref/tool isn't part of the spec;
+// servers that don't know it answer with an error.
+const (
+	RefPrompt   = "ref/prompt"
+	RefResource = "ref/resource"
+	RefTool     = "ref/tool"
+)
+
+// ServerCapabilities lists the optional features a server offers. only the
+// ones mcpfs acts on are kept.
+type ServerCapabilities struct {
+	Completions *struct{} `json:"completions,omitempty"`
+}
+
+type CompletionRef struct {
 	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Name string `json:"name,omitempty"`
+	URI  string `json:"uri,omitempty"`
+}
+
+type CompletionArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
 }
 
+// Handlers answer requests a server sends back to the client, possibly in
+// the middle of a tool call. a nil handler leaves the capability undeclared.
+type Handlers struct {
+	Elicit func(ctx context.Context, req *ElicitRequest) (*ElicitResult, error)
+	Roots  func(ctx context.Context) ([]Root, error)
+}
+
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+type listRootsResult struct {
+	Roots []Root `json:"roots"`
+}
+
+type ElicitRequest struct {
+	Message         string          `json:"message"`
+	RequestedSchema json.RawMessage `json:"requestedSchema,omitempty"`
+}
+
+type ElicitResult struct {
+	Action  string         `json:"action"`
+	Content map[string]any `json:"content,omitempty"`
+}
+
+const (
+	ElicitAccept  = "accept"
+	ElicitDecline = "decline"
+	ElicitCancel  = "cancel"
+)
+
 type jsonRPCRequest struct {
 	JSONRPC string `json:"jsonrpc"`
 	ID      int64  `json:"id"`
@@ -54,13 +161,63 @@ func (e *jsonRPCError) Error() string {
 	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
 }
 
+// jsonRPCMessage is anything a server can send: a response to one of our
+// requests, a request of its own, or a notification.
+type jsonRPCMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
+func (m *jsonRPCMessage) isRequest() bool {
+	return m.Method != "" && len(m.ID) > 0
+}
+
+func (m *jsonRPCMessage) isNotification() bool {
+	return m.Method != "" && len(m.ID) == 0
+}
+
+func (m *jsonRPCMessage) response() *jsonRPCResponse {
+	var id int64
+	json.Unmarshal(m.ID, &id)
+	return &jsonRPCResponse{
+		JSONRPC: m.JSONRPC,
+		ID:      id,
+		Result:  m.Result,
+		Error:   m.Error,
+	}
+}
+
+type jsonRPCReply struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *jsonRPCError   `json:"error,omitempty"`
+}
+
+const (
+	errMethodNotFound = -32601
+	errInvalidParams  = -32602
+	errInternal       = -32603
+)
+
 type initializeParams struct {
-	ProtocolVersion string           `json:"protocolVersion"`
-	Capabilities    clientCaps       `json:"capabilities"`
-	ClientInfo      clientInfo       `json:"clientInfo"`
+	ProtocolVersion string     `json:"protocolVersion"`
+	Capabilities    clientCaps `json:"capabilities"`
+	ClientInfo      clientInfo `json:"clientInfo"`
 }
 
-type clientCaps struct{}
+type clientCaps struct {
+	Elicitation *struct{}  `json:"elicitation,omitempty"`
+	Roots       *rootsCaps `json:"roots,omitempty"`
+}
+
+type rootsCaps struct {
+	ListChanged bool `json:"listChanged"`
+}
 
 type clientInfo struct {
 	Name    string `json:"name"`
@@ -68,8 +225,9 @@ type clientInfo struct {
 }
 
 type initializeResult struct {
-	ProtocolVersion string     `json:"protocolVersion"`
-	ServerInfo      serverInfo `json:"serverInfo"`
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      serverInfo         `json:"serverInfo"`
 }
 
 type serverInfo struct {
@@ -86,8 +244,25 @@ type callToolParams struct {
 	Arguments map[string]any `json:"arguments,omitempty"`
 }
 
+type completeParams struct {
+	Ref      CompletionRef      `json:"ref"`
+	Argument CompletionArgument `json:"argument"`
+}
+
+type completeResult struct {
+	Completion Completion `json:"completion"`
+}
+
+const protocolVersion = "2025-06-18"
+
 type baseClient struct {
-	reqID atomic.Int64
+	reqID    atomic.Int64
+	handlers Handlers
+	caps     ServerCapabilities
+}
+
+func (c *baseClient) Capabilities() ServerCapabilities {
+	return c.caps
 }
 
 func (c *baseClient) nextID() int64 {
@@ -103,13 +278,78 @@ func (c *baseClient) makeRequest(method string, params any) *jsonRPCRequest {
 	}
 }
 
+func (c *baseClient) makeNotification(method string, params any) map[string]any {
+	msg := map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+	}
+	if params != nil {
+		msg["params"] = params
+	}
+	return msg
+}
+
 func (c *baseClient) initParams() *initializeParams {
+	var caps clientCaps
+	if c.handlers.Elicit != nil {
+		caps.Elicitation = &struct{}{}
+	}
+	if c.handlers.Roots != nil {
+		caps.Roots = &rootsCaps{ListChanged: true}
+	}
 	return &initializeParams{
-		ProtocolVersion: "2024-11-05",
-		Capabilities:    clientCaps{},
+		ProtocolVersion: protocolVersion,
+		Capabilities:    caps,
 		ClientInfo: clientInfo{
 			Name:    "mcpfs",
 			Version: "0.1.0",
 		},
 	}
 }
+
+// handleRequest answers a server-initiated request with whichever handler
+// is registered for it.
+func (c *baseClient) handleRequest(ctx context.Context, msg *jsonRPCMessage) *jsonRPCReply {
+	reply := &jsonRPCReply{JSONRPC: "2.0", ID: msg.ID}
+
+	switch msg.Method {
+	case "elicitation/create":
+		if c.handlers.Elicit == nil {
+			break
+		}
+		var req ElicitRequest
+		if err := json.Unmarshal(msg.Params, &req); err != nil {
+			reply.Error = &jsonRPCError{Code: errInvalidParams, Message: err.Error()}
+			return reply
+		}
+		result, err := c.handlers.Elicit(ctx, &req)
+		if err != nil {
+			reply.Error = &jsonRPCError{Code: errInternal, Message: err.Error()}
+			return reply
+		}
+		reply.Result = result
+		return reply
+
+	case "roots/list":
+		if c.handlers.Roots == nil {
+			break
+		}
+		roots, err := c.handlers.Roots(ctx)
+		if err != nil {
+			reply.Error = &jsonRPCError{Code: errInternal, Message: err.Error()}
+			return reply
+		}
+		if roots == nil {
+			roots = []Root{}
+		}
+		reply.Result = listRootsResult{Roots: roots}
+		return reply
+
+	case "ping":
+		reply.Result = struct{}{}
+		return reply
+	}
+
+	reply.Error = &jsonRPCError{Code: errMethodNotFound, Message: "method not found: " + msg.Method}
+	return reply
+}
@@ -1,11 +1,17 @@
 package mcp
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHTTPClient(t *testing.T) {
@@ -23,6 +29,7 @@ func TestHTTPClient(t *testing.T) {
 		case "initialize":
 			result = initializeResult{
 				ProtocolVersion: "2024-11-05",
+				Capabilities:    ServerCapabilities{Completions: &struct{}{}},
 				ServerInfo:      serverInfo{Name: "test-server", Version: "1.0"},
 			}
 		case "tools/list":
@@ -58,6 +65,9 @@ func TestHTTPClient(t *testing.T) {
 	if err := client.Initialize(ctx); err != nil {
 		t.Fatalf("initialize: %v", err)
 	}
+	if client.Capabilities().Completions == nil {
+		t.Error("expected the completions capability to be recorded")
+	}
 
 	gotTools, err := client.ListTools(ctx)
 	if err != nil {
@@ -118,3 +128,272 @@ func TestBaseClientRequestID(t *testing.T) {
 		t.Errorf("expected sequential IDs, got %d, %d, %d", id1, id2, id3)
 	}
 }
+
+func TestHTTPClientElicitation(t *testing.T) {
+	replies := make(chan jsonRPCMessage, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg jsonRPCMessage
+		json.NewDecoder(r.Body).Decode(&msg)
+
+		if msg.Method == "" {
+			replies <- msg
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if msg.Method != "tools/call" {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		elicit, _ := json.Marshal(map[string]any{
+			"jsonrpc": "2.0",
+			"id":      "e1",
+			"method":  "elicitation/create",
+			"params": ElicitRequest{
+				Message:         "which repo?",
+				RequestedSchema: json.RawMessage(`{"type":"object","properties":{"repo":{"type":"string"}}}`),
+			},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", elicit)
+		w.(http.Flusher).Flush()
+
+		reply := <-replies
+		var answer ElicitResult
+		json.Unmarshal(reply.Result, &answer)
+
+		resp := jsonRPCResponse{JSONRPC: "2.0"}
+		json.Unmarshal(msg.ID, &resp.ID)
+		resp.Result, _ = json.Marshal(ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: answer.Action + " " + answer.Content["repo"].(string)}},
+		})
+		data, _ := json.Marshal(resp)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}))
+	defer server.Close()
+
+	var asked string
+	client := NewHTTPClient(HTTPConfig{
+		URL: server.URL,
+		Handlers: Handlers{
+			Elicit: func(ctx context.Context, req *ElicitRequest) (*ElicitResult, error) {
+				asked = req.Message
+				return &ElicitResult{Action: ElicitAccept, Content: map[string]any{"repo": "caffeinum/mcpfs"}}, nil
+			},
+		},
+	})
+
+	result, err := client.CallTool(context.Background(), "create_issue", nil)
+	if err != nil {
+		t.Fatalf("call tool: %v", err)
+	}
+	if asked != "which repo?" {
+		t.Errorf("expected elicitation message, got %q", asked)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "accept caffeinum/mcpfs" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestHTTPClientStreamOutlivesTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req jsonRPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+
+		resp := jsonRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`{"content":[{"type":"text","text":"done"}]}`)}
+		data, _ := json.Marshal(resp)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPConfig{URL: server.URL, Timeout: 50 * time.Millisecond})
+	result, err := client.CallTool(context.Background(), "slow", nil)
+	if err != nil {
+		t.Fatalf("expected the stream to outlive the timeout, got %v", err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "done" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestElicitationCapability(t *testing.T) {
+	var c baseClient
+	if c.initParams().Capabilities.Elicitation != nil {
+		t.Error("expected no elicitation capability without a handler")
+	}
+
+	c.handlers.Elicit = func(ctx context.Context, req *ElicitRequest) (*ElicitResult, error) {
+		return &ElicitResult{Action: ElicitDecline}, nil
+	}
+	if c.initParams().Capabilities.Elicitation == nil {
+		t.Error("expected elicitation capability with a handler")
+	}
+}
+
+func TestHandleRootsList(t *testing.T) {
+	c := baseClient{handlers: Handlers{
+		Roots: func(ctx context.Context) ([]Root, error) {
+			return []Root{{URI: "file:///work/repo", Name: "repo"}}, nil
+		},
+	}}
+
+	caps := c.initParams().Capabilities
+	if caps.Roots == nil || !caps.Roots.ListChanged {
+		t.Fatalf("expected roots capability with listChanged, got %+v", caps.Roots)
+	}
+
+	reply := c.handleRequest(context.Background(), &jsonRPCMessage{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(`7`),
+		Method:  "roots/list",
+	})
+	if reply.Error != nil {
+		t.Fatalf("unexpected error: %v", reply.Error)
+	}
+	result, ok := reply.Result.(listRootsResult)
+	if !ok || len(result.Roots) != 1 || result.Roots[0].URI != "file:///work/repo" {
+		t.Errorf("unexpected roots reply: %+v", reply.Result)
+	}
+	if string(reply.ID) != "7" {
+		t.Errorf("expected reply id 7, got %s", reply.ID)
+	}
+}
+
+func TestHandleUnknownRequest(t *testing.T) {
+	var c baseClient
+	reply := c.handleRequest(context.Background(), &jsonRPCMessage{
+		JSONRPC: "2.0",
+		ID:      json.RawMessage(`"x"`),
+		Method:  "sampling/createMessage",
+	})
+	if reply.Error == nil || reply.Error.Code != errMethodNotFound {
+		t.Errorf("expected method not found, got %+v", reply)
+	}
+}
+
+func TestHTTPClientComplete(t *testing.T) {
+	var got completeParams
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			jsonRPCRequest
+			Params completeParams `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		got = req.Params
+
+		resp := jsonRPCResponse{JSONRPC: "2.0", ID: req.ID}
+		resp.Result, _ = json.Marshal(completeResult{
+			Completion: Completion{Values: []string{"caffeinum/mcpfs", "caffeinum/other"}, Total: 2},
+		})
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPConfig{URL: server.URL})
+	completion, err := client.Complete(context.Background(),
+		CompletionRef{Type: RefTool, Name: "search"},
+		CompletionArgument{Name: "repo", Value: "caff"})
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	if got.Ref.Name != "search" || got.Argument.Name != "repo" || got.Argument.Value != "caff" {
+		t.Errorf("unexpected params: %+v", got)
+	}
+	if len(completion.Values) != 2 || completion.Values[0] != "caffeinum/mcpfs" {
+		t.Errorf("unexpected completion: %+v", completion)
+	}
+}
+
+func TestToolAnnotations(t *testing.T) {
+	var tools []Tool
+	json.Unmarshal([]byte(`[
+		{"name": "list", "annotations": {"title": "List", "readOnlyHint": true}},
+		{"name": "delete", "annotations": {"destructiveHint": true, "idempotentHint": true}},
+		{"name": "create", "annotations": {"readOnlyHint": false, "destructiveHint": false}},
+		{"name": "update", "annotations": {"openWorldHint": false}},
+		{"name": "plain"}
+	]`), &tools)
+
+	tests := []struct {
+		readOnly    bool
+		destructive bool
+	}{
+		{true, false},
+		{false, true},
+		{false, false},
+		{false, true}, // destructiveHint defaults to true
+		{false, false},
+	}
+	for i, tt := range tests {
+		if got := tools[i].ReadOnly(); got != tt.readOnly {
+			t.Errorf("%s: ReadOnly() = %v, want %v", tools[i].Name, got, tt.readOnly)
+		}
+		if got := tools[i].Destructive(); got != tt.destructive {
+			t.Errorf("%s: Destructive() = %v, want %v", tools[i].Name, got, tt.destructive)
+		}
+	}
+
+	if tools[0].Annotations.Title != "List" {
+		t.Errorf("expected title, got %+v", tools[0].Annotations)
+	}
+}
+
+func TestHTTPClientUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="https://example.com/.well-known/oauth-protected-resource"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(HTTPConfig{URL: server.URL})
+	err := client.Initialize(context.Background())
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expected HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusUnauthorized || !strings.Contains(httpErr.WWWAuthenticate, "resource_metadata") {
+		t.Errorf("unexpected error: %+v", httpErr)
+	}
+}
+
+func TestStdioNotifyDuringRequest(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	defer outW.Close()
+	c := &StdioClient{stdin: inW, stdout: bufio.NewReader(outR)}
+
+	lines := make(chan string, 2)
+	go func() {
+		scanner := bufio.NewScanner(inR)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// a request the server never answers, like one waiting on an elicitation
+	go c.send(context.Background(), c.makeRequest("tools/call", nil))
+	<-lines
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Notify(context.Background(), "notifications/roots/list_changed", nil)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("notify: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notify waited for the pending request")
+	}
+	if line := <-lines; !strings.Contains(line, "roots/list_changed") {
+		t.Errorf("expected the notification written, got %s", line)
+	}
+}
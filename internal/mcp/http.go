@@ -1,28 +1,46 @@
 package mcp
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
 
 type HTTPClient struct {
 	baseClient
-	url     string
-	headers map[string]string
-	client  *http.Client
-	mu      sync.Mutex
+	url       string
+	headers   map[string]string
+	client    *http.Client
+	timeout   time.Duration
+	sessionID string
+	mu        sync.Mutex
+}
+
+// HTTPError is a non-200 answer from the server. 401s carry the
+// WWW-Authenticate challenge that points at the OAuth metadata.
+type HTTPError struct {
+	StatusCode      int
+	Body            string
+	WWWAuthenticate string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http %d: %s", e.StatusCode, e.Body)
 }
 
 type HTTPConfig struct {
-	URL     string
-	Headers map[string]string
-	Timeout time.Duration
+	URL      string
+	Headers  map[string]string
+	Timeout  time.Duration
+	Handlers Handlers
 }
 
 func NewHTTPClient(cfg HTTPConfig) *HTTPClient {
@@ -32,11 +50,11 @@ func NewHTTPClient(cfg HTTPConfig) *HTTPClient {
 	}
 
 	return &HTTPClient{
-		url:     cfg.URL,
-		headers: cfg.Headers,
-		client: &http.Client{
-			Timeout: timeout,
-		},
+		baseClient: baseClient{handlers: cfg.Handlers},
+		url:        cfg.URL,
+		headers:    cfg.Headers,
+		client:     &http.Client{},
+		timeout:    timeout,
 	}
 }
 
@@ -44,7 +62,61 @@ func (c *HTTPClient) send(ctx context.Context, req *jsonRPCRequest) (*jsonRPCRes
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	data, err := json.Marshal(req)
+	// the timeout covers a plain answer; an event stream stays open as long
+	// as the server needs, e.g. while a question in .elicit is pending
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	timer := time.AfterFunc(c.timeout, cancel)
+	defer timer.Stop()
+
+	httpResp, err := c.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if sid := httpResp.Header.Get("Mcp-Session-Id"); sid != "" {
+		c.sessionID = sid
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(httpResp.Body)
+		return nil, &HTTPError{
+			StatusCode:      httpResp.StatusCode,
+			Body:            string(body),
+			WWWAuthenticate: httpResp.Header.Get("WWW-Authenticate"),
+		}
+	}
+
+	var resp *jsonRPCResponse
+	mediaType, _, _ := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		timer.Stop()
+		resp, err = c.readStream(ctx, httpResp.Body, req.ID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		respData, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+
+		resp = &jsonRPCResponse{}
+		if err := json.Unmarshal(respData, resp); err != nil {
+			return nil, fmt.Errorf("unmarshal response: %w", err)
+		}
+	}
+
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	return resp, nil
+}
+
+func (c *HTTPClient) post(ctx context.Context, msg any) (*http.Response, error) {
+	data, err := json.Marshal(msg)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
@@ -55,6 +127,10 @@ func (c *HTTPClient) send(ctx context.Context, req *jsonRPCRequest) (*jsonRPCRes
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json, text/event-stream")
+	if c.sessionID != "" {
+		httpReq.Header.Set("Mcp-Session-Id", c.sessionID)
+	}
 	for k, v := range c.headers {
 		httpReq.Header.Set(k, v)
 	}
@@ -63,28 +139,82 @@ func (c *HTTPClient) send(ctx context.Context, req *jsonRPCRequest) (*jsonRPCRes
 	if err != nil {
 		return nil, fmt.Errorf("http request: %w", err)
 	}
-	defer httpResp.Body.Close()
+	return httpResp, nil
+}
 
-	if httpResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(httpResp.Body)
-		return nil, fmt.Errorf("http %d: %s", httpResp.StatusCode, string(body))
-	}
+// readStream consumes an SSE response until the reply to id arrives,
+// answering any requests the server sends in the meantime.
+func (c *HTTPClient) readStream(ctx context.Context, body io.Reader, id int64) (*jsonRPCResponse, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
 
-	respData, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if after, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(after, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var msg jsonRPCMessage
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err != nil {
+			return nil, fmt.Errorf("unmarshal event: %w", err)
+		}
+
+		if msg.isNotification() {
+			continue
+		}
+
+		if msg.isRequest() {
+			if err := c.reply(ctx, c.handleRequest(ctx, &msg)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if resp := msg.response(); resp.ID == id {
+			return resp, nil
+		}
 	}
 
-	var resp jsonRPCResponse
-	if err := json.Unmarshal(respData, &resp); err != nil {
-		return nil, fmt.Errorf("unmarshal response: %w", err)
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read stream: %w", err)
 	}
+	return nil, fmt.Errorf("stream closed before response")
+}
 
-	if resp.Error != nil {
-		return nil, resp.Error
+func (c *HTTPClient) reply(ctx context.Context, reply *jsonRPCReply) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	httpResp, err := c.post(ctx, reply)
+	if err != nil {
+		return fmt.Errorf("send reply: %w", err)
 	}
+	io.Copy(io.Discard, httpResp.Body)
+	httpResp.Body.Close()
+	return nil
+}
+
+func (c *HTTPClient) Notify(ctx context.Context, method string, params any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return &resp, nil
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	httpResp, err := c.post(ctx, c.makeNotification(method, params))
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, httpResp.Body)
+	httpResp.Body.Close()
+	return nil
 }
 
 func (c *HTTPClient) Initialize(ctx context.Context) error {
@@ -98,6 +228,9 @@ func (c *HTTPClient) Initialize(ctx context.Context) error {
 	if err := json.Unmarshal(resp.Result, &result); err != nil {
 		return fmt.Errorf("parse initialize result: %w", err)
 	}
+	c.caps = result.Capabilities
+
+	c.Notify(ctx, "notifications/initialized", nil)
 
 	return nil
 }
@@ -132,10 +265,29 @@ func (c *HTTPClient) CallTool(ctx context.Context, name string, args map[string]
 	if err := json.Unmarshal(resp.Result, &result); err != nil {
 		return nil, fmt.Errorf("parse tool result: %w", err)
 	}
+	result.Raw = resp.Result
 
 	return &result, nil
 }
 
+func (c *HTTPClient) Complete(ctx context.Context, ref CompletionRef, arg CompletionArgument) (*Completion, error) {
+	req := c.makeRequest("completion/complete", completeParams{
+		Ref:      ref,
+		Argument: arg,
+	})
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("complete: %w", err)
+	}
+
+	var result completeResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("parse completion: %w", err)
+	}
+
+	return &result.Completion, nil
+}
+
 func (c *HTTPClient) Close() error {
 	return nil
 }
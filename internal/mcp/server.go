@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Backend is what Serve exposes to the client on the other end, usually a
+// connection to another server.
+type Backend interface {
+	ListTools(ctx context.Context) ([]Tool, error)
+	CallTool(ctx context.Context, name string, args map[string]any) (*ToolResult, error)
+}
+
+const errParse = -32700
+
+// protocol versions Serve can answer with
+var supportedVersions = map[string]bool{
+	"2024-11-05":    true,
+	"2025-03-26":    true,
+	protocolVersion: true,
+}
+
+type serverCaps struct {
+	Tools struct{} `json:"tools"`
+}
+
+type serveInitializeResult struct {
+	ProtocolVersion string     `json:"protocolVersion"`
+	Capabilities    serverCaps `json:"capabilities"`
+	ServerInfo      serverInfo `json:"serverInfo"`
+}
+
+// Serve speaks mcp over newline-delimited json-rpc, answering requests from
+// r on w until r is closed. tool failures are returned as isError results so
+// the model sees them; requests are handled concurrently.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, name string, backend Backend) error {
+	var mu sync.Mutex
+	write := func(reply *jsonRPCReply) {
+		data, err := json.Marshal(reply)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		w.Write(append(data, '\n'))
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var msg jsonRPCMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			write(&jsonRPCReply{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &jsonRPCError{Code: errParse, Message: err.Error()}})
+			continue
+		}
+		// notifications and replies to requests we never send
+		if !msg.isRequest() {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			write(serveRequest(ctx, &msg, name, backend))
+		}()
+	}
+	return scanner.Err()
+}
+
+func serveRequest(ctx context.Context, msg *jsonRPCMessage, name string, backend Backend) *jsonRPCReply {
+	reply := &jsonRPCReply{JSONRPC: "2.0", ID: msg.ID}
+
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		json.Unmarshal(msg.Params, &params)
+		// answer with the client's version only if we speak it; otherwise
+		// offer ours and let the client decide
+		version := protocolVersion
+		if supportedVersions[params.ProtocolVersion] {
+			version = params.ProtocolVersion
+		}
+		reply.Result = serveInitializeResult{
+			ProtocolVersion: version,
+			ServerInfo:      serverInfo{Name: name, Version: "0.1.0"},
+		}
+
+	case "ping":
+		reply.Result = struct{}{}
+
+	case "tools/list":
+		tools, err := backend.ListTools(ctx)
+		if err != nil {
+			reply.Error = &jsonRPCError{Code: errInternal, Message: err.Error()}
+			break
+		}
+		if tools == nil {
+			tools = []Tool{}
+		}
+		reply.Result = listToolsResult{Tools: tools}
+
+	case "tools/call":
+		var params callToolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+			reply.Error = &jsonRPCError{Code: errInvalidParams, Message: "tools/call needs a tool name"}
+			break
+		}
+		res, err := backend.CallTool(ctx, params.Name, params.Arguments)
+		switch {
+		case err != nil:
+			reply.Result = &ToolResult{
+				Content: []ContentBlock{{Type: "text", Text: err.Error()}},
+				IsError: true,
+			}
+		case len(res.Raw) > 0:
+			reply.Result = res.Raw
+		default:
+			reply.Result = res
+		}
+
+	default:
+		reply.Error = &jsonRPCError{Code: errMethodNotFound, Message: fmt.Sprintf("method not found: %s", msg.Method)}
+	}
+	return reply
+}
@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeBackend struct{}
+
+func (fakeBackend) ListTools(ctx context.Context) ([]Tool, error) {
+	return []Tool{{Name: "echo"}}, nil
+}
+
+func (fakeBackend) CallTool(ctx context.Context, name string, args map[string]any) (*ToolResult, error) {
+	if name != "echo" {
+		return nil, errors.New("no such tool")
+	}
+	return &ToolResult{Raw: json.RawMessage(`{"content":[{"type":"text","text":"` + args["text"].(string) + `"}],"structuredContent":{"ok":true}}`)}, nil
+}
+
+func TestServe(t *testing.T) {
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := Serve(context.Background(), strings.NewReader(in), &out, "proxy", fakeBackend{}); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	replies := make(map[string]jsonRPCMessage)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg jsonRPCMessage
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("bad reply %q: %v", line, err)
+		}
+		replies[string(msg.ID)] = msg
+	}
+	if len(replies) != 6 {
+		t.Fatalf("expected 6 replies, got %d: %s", len(replies), out.String())
+	}
+
+	var init serveInitializeResult
+	json.Unmarshal(replies["1"].Result, &init)
+	if init.ProtocolVersion != "2025-03-26" || init.ServerInfo.Name != "proxy" {
+		t.Errorf("unexpected initialize result: %+v", init)
+	}
+
+	var list listToolsResult
+	json.Unmarshal(replies["2"].Result, &list)
+	if len(list.Tools) != 1 || list.Tools[0].Name != "echo" {
+		t.Errorf("unexpected tools: %+v", list)
+	}
+
+	if !strings.Contains(string(replies["3"].Result), `"structuredContent":{"ok":true}`) {
+		t.Errorf("expected the raw result passed through, got %s", replies["3"].Result)
+	}
+
+	var failed ToolResult
+	json.Unmarshal(replies["4"].Result, &failed)
+	if !failed.IsError || failed.Content[0].Text != "no such tool" {
+		t.Errorf("expected an isError result, got %s", replies["4"].Result)
+	}
+
+	if e := replies["5"].Error; e == nil || e.Code != errMethodNotFound {
+		t.Errorf("expected method not found, got %+v", replies["5"])
+	}
+	if e := replies["null"].Error; e == nil || e.Code != errParse {
+		t.Errorf("expected parse error, got %+v", replies["null"])
+	}
+}
+
+func TestServeUnknownProtocolVersion(t *testing.T) {
+	in := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`
+
+	var out bytes.Buffer
+	if err := Serve(context.Background(), strings.NewReader(in), &out, "proxy", fakeBackend{}); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	var msg jsonRPCMessage
+	if err := json.Unmarshal(out.Bytes(), &msg); err != nil {
+		t.Fatalf("bad reply %q: %v", out.String(), err)
+	}
+	var init serveInitializeResult
+	json.Unmarshal(msg.Result, &init)
+	if init.ProtocolVersion != protocolVersion {
+		t.Errorf("expected %s for an unknown version, got %q", protocolVersion, init.ProtocolVersion)
+	}
+}
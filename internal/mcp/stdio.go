@@ -12,17 +12,20 @@ import (
 
 type StdioClient struct {
 	baseClient
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout *bufio.Reader
-	mu     sync.Mutex
-	closed bool
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	mu      sync.Mutex // held for a request until its answer arrives
+	writeMu sync.Mutex // held per line written, guards closed
+	closed  bool
 }
 
 type StdioConfig struct {
-	Command string
-	Args    []string
-	Env     []string
+	Command  string
+	Args     []string
+	Env      []string
+	Stderr   io.Writer // server's stderr, discarded when nil
+	Handlers Handlers
 }
 
 func NewStdioClient(cfg StdioConfig) (*StdioClient, error) {
@@ -30,6 +33,7 @@ func NewStdioClient(cfg StdioConfig) (*StdioClient, error) {
 	if len(cfg.Env) > 0 {
 		cmd.Env = cfg.Env
 	}
+	cmd.Stderr = cfg.Stderr
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -48,49 +52,71 @@ func NewStdioClient(cfg StdioConfig) (*StdioClient, error) {
 	}
 
 	return &StdioClient{
-		cmd:    cmd,
-		stdin:  stdin,
-		stdout: bufio.NewReader(stdout),
+		baseClient: baseClient{handlers: cfg.Handlers},
+		cmd:        cmd,
+		stdin:      stdin,
+		stdout:     bufio.NewReader(stdout),
 	}, nil
 }
 
-func (c *StdioClient) send(req *jsonRPCRequest) (*jsonRPCResponse, error) {
+func (c *StdioClient) send(ctx context.Context, req *jsonRPCRequest) (*jsonRPCResponse, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.closed {
-		return nil, fmt.Errorf("client closed")
-	}
-
 	data, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+	if err := c.write(data); err != nil {
 		return nil, fmt.Errorf("write request: %w", err)
 	}
 
-	line, err := c.stdout.ReadBytes('\n')
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
-	var resp jsonRPCResponse
-	if err := json.Unmarshal(line, &resp); err != nil {
-		return nil, fmt.Errorf("unmarshal response: %w", err)
-	}
-
-	if resp.Error != nil {
-		return nil, resp.Error
+	// the server may interleave its own requests (elicitation) and
+	// notifications before it answers ours
+	for {
+		line, err := c.stdout.ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+
+		var msg jsonRPCMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, fmt.Errorf("unmarshal response: %w", err)
+		}
+
+		if msg.isNotification() {
+			continue
+		}
+
+		if msg.isRequest() {
+			reply := c.handleRequest(ctx, &msg)
+			data, err := json.Marshal(reply)
+			if err != nil {
+				return nil, fmt.Errorf("marshal reply: %w", err)
+			}
+			if err := c.write(data); err != nil {
+				return nil, fmt.Errorf("write reply: %w", err)
+			}
+			continue
+		}
+
+		resp := msg.response()
+		if resp.ID != req.ID {
+			continue
+		}
+
+		if resp.Error != nil {
+			return nil, resp.Error
+		}
+
+		return resp, nil
 	}
-
-	return &resp, nil
 }
 
 func (c *StdioClient) Initialize(ctx context.Context) error {
 	req := c.makeRequest("initialize", c.initParams())
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return fmt.Errorf("initialize: %w", err)
 	}
@@ -99,22 +125,40 @@ func (c *StdioClient) Initialize(ctx context.Context) error {
 	if err := json.Unmarshal(resp.Result, &result); err != nil {
 		return fmt.Errorf("parse initialize result: %w", err)
 	}
+	c.caps = result.Capabilities
+
+	return c.Notify(ctx, "notifications/initialized", nil)
+}
 
-	notify := map[string]any{
-		"jsonrpc": "2.0",
-		"method":  "notifications/initialized",
+func (c *StdioClient) Notify(ctx context.Context, method string, params any) error {
+	data, err := json.Marshal(c.makeNotification(method, params))
+	if err != nil {
+		return fmt.Errorf("marshal notification: %w", err)
 	}
-	data, _ := json.Marshal(notify)
-	c.mu.Lock()
-	c.stdin.Write(append(data, '\n'))
-	c.mu.Unlock()
 
+	// only the write lock: a request may be waiting minutes on an
+	// elicitation, and a notification shouldn't wait with it
+	if err := c.write(data); err != nil {
+		return fmt.Errorf("write notification: %w", err)
+	}
 	return nil
 }
 
+// write sends one message as a line on the server's stdin.
+func (c *StdioClient) write(data []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closed {
+		return fmt.Errorf("client closed")
+	}
+	_, err := c.stdin.Write(append(data, '\n'))
+	return err
+}
+
 func (c *StdioClient) ListTools(ctx context.Context) ([]Tool, error) {
 	req := c.makeRequest("tools/list", nil)
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("list tools: %w", err)
 	}
@@ -133,7 +177,7 @@ func (c *StdioClient) CallTool(ctx context.Context, name string, args map[string
 		Arguments: args,
 	}
 	req := c.makeRequest("tools/call", params)
-	resp, err := c.send(req)
+	resp, err := c.send(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("call tool: %w", err)
 	}
@@ -142,19 +186,41 @@ func (c *StdioClient) CallTool(ctx context.Context, name string, args map[string
 	if err := json.Unmarshal(resp.Result, &result); err != nil {
 		return nil, fmt.Errorf("parse tool result: %w", err)
 	}
+	result.Raw = resp.Result
 
 	return &result, nil
 }
 
+func (c *StdioClient) Complete(ctx context.Context, ref CompletionRef, arg CompletionArgument) (*Completion, error) {
+	req := c.makeRequest("completion/complete", completeParams{
+		Ref:      ref,
+		Argument: arg,
+	})
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("complete: %w", err)
+	}
+
+	var result completeResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("parse completion: %w", err)
+	}
+
+	return &result.Completion, nil
+}
+
 func (c *StdioClient) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.writeMu.Lock()
 	if c.closed {
+		c.writeMu.Unlock()
 		return nil
 	}
 	c.closed = true
-
 	c.stdin.Close()
+	c.writeMu.Unlock()
+
 	return c.cmd.Wait()
 }
@@ -0,0 +1,494 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+)
+
+// the MCP authorization flow: a 401 from the server points at protected
+// resource metadata (RFC 9728), which names the authorization server (RFC
+// 8414). mcpfs registers itself dynamically (RFC 7591) and gets a code via
+// PKCE and a loopback redirect, then keeps the tokens in the auth store.
+
+// keys used in config.Auth. token is what `Bearer ${auth.token}` picks up.
+const (
+	KeyToken         = "token"
+	KeyRefreshToken  = "refresh_token"
+	KeyExpiresAt     = "expires_at"
+	KeyClientID      = "oauth_client_id"
+	KeyClientSecret  = "oauth_client_secret"
+	KeyTokenEndpoint = "oauth_token_endpoint"
+	KeyResource      = "oauth_resource"
+)
+
+const clientName = "mcpfs"
+
+type ResourceMetadata struct {
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"`
+	ScopesSupported      []string `json:"scopes_supported,omitempty"`
+}
+
+type ServerMetadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	RegistrationEndpoint          string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+}
+
+type Client struct {
+	ID     string `json:"client_id"`
+	Secret string `json:"client_secret,omitempty"`
+}
+
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// Flow carries what Login needs besides the server url.
+type Flow struct {
+	HTTP *http.Client
+	// Open shows the authorization url to the user, usually in a browser
+	Open  func(authURL string) error
+	Scope string
+}
+
+// Session is the outcome of a login, everything needed to refresh later.
+type Session struct {
+	Token         *Token
+	Client        *Client
+	TokenEndpoint string
+	Resource      string
+}
+
+var resourceMetadataParam = regexp.MustCompile(`resource_metadata="([^"]+)"`)
+
+// ResourceMetadataURL extracts resource_metadata from a WWW-Authenticate
+// challenge.
+func ResourceMetadataURL(challenge string) string {
+	if m := resourceMetadataParam.FindStringSubmatch(challenge); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func (f *Flow) http() *http.Client {
+	if f.HTTP != nil {
+		return f.HTTP
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// Login runs the whole authorization code flow against serverURL.
+func (f *Flow) Login(ctx context.Context, serverURL string) (*Session, error) {
+	resource, err := f.DiscoverResource(ctx, serverURL)
+	if err != nil {
+		return nil, err
+	}
+	if len(resource.AuthorizationServers) == 0 {
+		return nil, fmt.Errorf("resource metadata names no authorization server")
+	}
+	meta, err := f.DiscoverServer(ctx, resource.AuthorizationServers[0])
+	if err != nil {
+		return nil, err
+	}
+
+	loopback, err := Listen()
+	if err != nil {
+		return nil, err
+	}
+	defer loopback.Close()
+
+	client, err := f.Register(ctx, meta, loopback.RedirectURI)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, challenge := NewPKCE()
+	state := randomString(16)
+	scope := f.Scope
+	if scope == "" {
+		scope = strings.Join(resource.ScopesSupported, " ")
+	}
+	authURL := AuthorizeURL(meta, client.ID, loopback.RedirectURI, challenge, state, resource.Resource, scope)
+
+	if f.Open != nil {
+		if err := f.Open(authURL); err != nil {
+			return nil, fmt.Errorf("open browser: %w", err)
+		}
+	}
+
+	code, err := loopback.Wait(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := f.Exchange(ctx, meta.TokenEndpoint, client, code, verifier, loopback.RedirectURI, resource.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Session{
+		Token:         token,
+		Client:        client,
+		TokenEndpoint: meta.TokenEndpoint,
+		Resource:      resource.Resource,
+	}, nil
+}
+
+// DiscoverResource probes the server for its 401 challenge and fetches the
+// protected resource metadata it points to, falling back to the well-known
+// location on the server's origin.
+func (f *Flow) DiscoverResource(ctx context.Context, serverURL string) (*ResourceMetadata, error) {
+	metaURL := ""
+	req, err := http.NewRequestWithContext(ctx, "POST", serverURL,
+		strings.NewReader(`{"jsonrpc":"2.0","id":0,"method":"ping"}`))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if resp, err := f.http().Do(req); err == nil {
+		resp.Body.Close()
+		metaURL = ResourceMetadataURL(resp.Header.Get("WWW-Authenticate"))
+	}
+
+	var candidates []string
+	if metaURL != "" {
+		candidates = append(candidates, metaURL)
+	}
+	candidates = append(candidates, wellKnown(serverURL, "oauth-protected-resource")...)
+
+	var meta ResourceMetadata
+	if err := f.getFirst(ctx, candidates, &meta); err != nil {
+		return nil, fmt.Errorf("protected resource metadata: %w", err)
+	}
+	if meta.Resource == "" {
+		meta.Resource = serverURL
+	}
+	return &meta, nil
+}
+
+// DiscoverServer fetches authorization server metadata, trying the OAuth
+// and then the OpenID well-known documents.
+func (f *Flow) DiscoverServer(ctx context.Context, issuer string) (*ServerMetadata, error) {
+	candidates := append(wellKnown(issuer, "oauth-authorization-server"),
+		wellKnown(issuer, "openid-configuration")...)
+
+	var meta ServerMetadata
+	if err := f.getFirst(ctx, candidates, &meta); err != nil {
+		return nil, fmt.Errorf("authorization server metadata: %w", err)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" {
+		return nil, fmt.Errorf("authorization server metadata is missing endpoints")
+	}
+	return &meta, nil
+}
+
+// Register creates a public client for the loopback redirect.
+func (f *Flow) Register(ctx context.Context, meta *ServerMetadata, redirectURI string) (*Client, error) {
+	if meta.RegistrationEndpoint == "" {
+		return nil, fmt.Errorf("authorization server doesn't support dynamic client registration")
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"client_name":                clientName,
+		"redirect_uris":              []string{redirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", meta.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var client Client
+	if err := f.do(req, &client); err != nil {
+		return nil, fmt.Errorf("register client: %w", err)
+	}
+	if client.ID == "" {
+		return nil, fmt.Errorf("register client: no client_id in response")
+	}
+	return &client, nil
+}
+
+func (f *Flow) Exchange(ctx context.Context, tokenEndpoint string, client *Client, code, verifier, redirectURI, resource string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {client.ID},
+		"code_verifier": {verifier},
+	}
+	if resource != "" {
+		form.Set("resource", resource)
+	}
+	return f.token(ctx, tokenEndpoint, client, form)
+}
+
+func (f *Flow) Refresh(ctx context.Context, tokenEndpoint string, client *Client, refreshToken, resource string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {client.ID},
+	}
+	if resource != "" {
+		form.Set("resource", resource)
+	}
+	return f.token(ctx, tokenEndpoint, client, form)
+}
+
+func (f *Flow) token(ctx context.Context, endpoint string, client *Client, form url.Values) (*Token, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if client.Secret != "" {
+		req.SetBasicAuth(url.QueryEscape(client.ID), url.QueryEscape(client.Secret))
+	}
+
+	var token Token
+	if err := f.do(req, &token); err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token request: no access_token in response")
+	}
+	return &token, nil
+}
+
+func (f *Flow) getFirst(ctx context.Context, urls []string, v any) error {
+	var lastErr error
+	for _, u := range urls {
+		req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		req.Header.Set("Accept", "application/json")
+		if lastErr = f.do(req, v); lastErr == nil {
+			return nil
+		}
+	}
+	return lastErr
+}
+
+func (f *Flow) do(req *http.Request, v any) error {
+	resp, err := f.http().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: http %d: %s", req.Method, req.URL, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("%s %s: %w", req.Method, req.URL, err)
+	}
+	return nil
+}
+
+// wellKnown builds RFC 8414 style urls: the well-known segment goes between
+// the host and the path, with the plain root as a fallback.
+func wellKnown(base, name string) []string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil
+	}
+	root := u.Scheme + "://" + u.Host + "/.well-known/" + name
+	if p := strings.TrimSuffix(u.Path, "/"); p != "" {
+		return []string{root + p, root}
+	}
+	return []string{root}
+}
+
+// NewPKCE returns a verifier and its S256 challenge.
+func NewPKCE() (verifier, challenge string) {
+	verifier = randomString(32)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func AuthorizeURL(meta *ServerMetadata, clientID, redirectURI, challenge, state, resource, scope string) string {
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+	}
+	if resource != "" {
+		q.Set("resource", resource)
+	}
+	if scope != "" {
+		q.Set("scope", scope)
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode()
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Loopback receives the authorization code on 127.0.0.1.
+type Loopback struct {
+	RedirectURI string
+	listener    net.Listener
+	server      *http.Server
+	result      chan callback
+}
+
+type callback struct {
+	code, state, err string
+}
+
+func Listen() (*Loopback, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen for redirect: %w", err)
+	}
+
+	l := &Loopback{
+		RedirectURI: "http://" + ln.Addr().String() + "/callback",
+		listener:    ln,
+		result:      make(chan callback, 1),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		cb := callback{code: q.Get("code"), state: q.Get("state"), err: q.Get("error")}
+		if desc := q.Get("error_description"); desc != "" {
+			cb.err += ": " + desc
+		}
+		select {
+		case l.result <- cb:
+		default:
+		}
+		if cb.err != "" {
+			fmt.Fprintf(w, "mcpfs: authorization failed (%s). you can close this tab.\n", cb.err)
+			return
+		}
+		fmt.Fprintln(w, "mcpfs: authorized. you can close this tab.")
+	})
+	l.server = &http.Server{Handler: mux}
+	go l.server.Serve(ln)
+
+	return l, nil
+}
+
+// Wait blocks until the browser comes back with a code for state.
+func (l *Loopback) Wait(ctx context.Context, state string) (string, error) {
+	select {
+	case cb := <-l.result:
+		if cb.err != "" {
+			return "", fmt.Errorf("authorization failed: %s", cb.err)
+		}
+		if cb.state != state {
+			return "", fmt.Errorf("authorization failed: state mismatch")
+		}
+		if cb.code == "" {
+			return "", fmt.Errorf("authorization failed: no code")
+		}
+		return cb.code, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (l *Loopback) Close() error {
+	return l.server.Close()
+}
+
+// Store merges a session into auth.
+func (s *Session) Store(auth *config.Auth) {
+	auth.Data[KeyToken] = s.Token.AccessToken
+	if s.Token.RefreshToken != "" {
+		auth.Data[KeyRefreshToken] = s.Token.RefreshToken
+	}
+	if s.Token.ExpiresIn > 0 {
+		auth.Data[KeyExpiresAt] = strconv.FormatInt(time.Now().Unix()+s.Token.ExpiresIn, 10)
+	} else {
+		delete(auth.Data, KeyExpiresAt)
+	}
+	auth.Data[KeyClientID] = s.Client.ID
+	if s.Client.Secret != "" {
+		auth.Data[KeyClientSecret] = s.Client.Secret
+	}
+	auth.Data[KeyTokenEndpoint] = s.TokenEndpoint
+	if s.Resource != "" {
+		auth.Data[KeyResource] = s.Resource
+	}
+}
+
+// CanRefresh reports whether auth holds what RefreshAuth needs.
+func CanRefresh(auth *config.Auth) bool {
+	return auth != nil && auth.Data[KeyRefreshToken] != "" && auth.Data[KeyTokenEndpoint] != "" && auth.Data[KeyClientID] != ""
+}
+
+// RefreshAuth trades the stored refresh token for a new access token and
+// updates auth in place. the caller saves it.
+func (f *Flow) RefreshAuth(ctx context.Context, auth *config.Auth) error {
+	if !CanRefresh(auth) {
+		return fmt.Errorf("no oauth refresh token stored")
+	}
+
+	// stored values may be keyring or command references
+	resolved, err := auth.Resolve()
+	if err != nil {
+		return err
+	}
+	client := &Client{ID: resolved.Data[KeyClientID], Secret: resolved.Data[KeyClientSecret]}
+	token, err := f.Refresh(ctx, resolved.Data[KeyTokenEndpoint], client, resolved.Data[KeyRefreshToken], resolved.Data[KeyResource])
+	if err != nil {
+		return err
+	}
+	if token.RefreshToken == "" {
+		// servers may keep the old refresh token valid
+		token.RefreshToken = resolved.Data[KeyRefreshToken]
+	}
+
+	session := &Session{
+		Token:         token,
+		Client:        client,
+		TokenEndpoint: resolved.Data[KeyTokenEndpoint],
+		Resource:      resolved.Data[KeyResource],
+	}
+	session.Store(auth)
+	return nil
+}
@@ -0,0 +1,171 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+)
+
+// mockAuth is a protected mcp endpoint plus an authorization server that
+// approves every request.
+type mockAuth struct {
+	server    *httptest.Server
+	mu        sync.Mutex
+	challenge string // code_challenge from the last authorize request
+	refreshes int
+}
+
+func newMockAuth(t *testing.T) *mockAuth {
+	m := &mockAuth{}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+m.server.URL+`/.well-known/oauth-protected-resource/mcp"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/.well-known/oauth-protected-resource/mcp", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ResourceMetadata{
+			Resource:             m.server.URL + "/mcp",
+			AuthorizationServers: []string{m.server.URL + "/auth"},
+			ScopesSupported:      []string{"read"},
+		})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server/auth", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(ServerMetadata{
+			Issuer:                m.server.URL + "/auth",
+			AuthorizationEndpoint: m.server.URL + "/auth/authorize",
+			TokenEndpoint:         m.server.URL + "/auth/token",
+			RegistrationEndpoint:  m.server.URL + "/auth/register",
+		})
+	})
+	mux.HandleFunc("/auth/register", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RedirectURIs []string `json:"redirect_uris"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.RedirectURIs) != 1 {
+			http.Error(w, "need a redirect uri", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Client{ID: "client-1"})
+	})
+	mux.HandleFunc("/auth/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("resource") != m.server.URL+"/mcp" || q.Get("scope") != "read" {
+			http.Error(w, "bad authorize request", http.StatusBadRequest)
+			return
+		}
+		m.mu.Lock()
+		m.challenge = q.Get("code_challenge")
+		m.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=code-1&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/auth/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			m.mu.Lock()
+			ok := base64.RawURLEncoding.EncodeToString(sum[:]) == m.challenge
+			m.mu.Unlock()
+			if r.PostForm.Get("code") != "code-1" || !ok {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(Token{AccessToken: "access-1", RefreshToken: "refresh-1", ExpiresIn: 3600, TokenType: "Bearer"})
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != "refresh-1" || r.PostForm.Get("client_id") != "client-1" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			m.mu.Lock()
+			m.refreshes++
+			m.mu.Unlock()
+			json.NewEncoder(w).Encode(Token{AccessToken: "access-2", TokenType: "Bearer"})
+		}
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func TestLogin(t *testing.T) {
+	m := newMockAuth(t)
+
+	// stands in for the browser: follow the authorize redirect to the loopback
+	flow := &Flow{
+		Open: func(authURL string) error {
+			go func() {
+				resp, err := http.Get(authURL)
+				if err == nil {
+					resp.Body.Close()
+				}
+			}()
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	session, err := flow.Login(ctx, m.server.URL+"/mcp")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if session.Token.AccessToken != "access-1" || session.Client.ID != "client-1" {
+		t.Errorf("unexpected session: %+v", session)
+	}
+
+	auth := &config.Auth{Data: map[string]string{"other": "kept"}}
+	session.Store(auth)
+	if auth.Data[KeyToken] != "access-1" || auth.Data[KeyRefreshToken] != "refresh-1" || auth.Data["other"] != "kept" {
+		t.Errorf("unexpected auth: %v", auth.Data)
+	}
+	if !CanRefresh(auth) {
+		t.Fatal("expected refreshable auth")
+	}
+
+	if err := flow.RefreshAuth(ctx, auth); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if auth.Data[KeyToken] != "access-2" || auth.Data[KeyRefreshToken] != "refresh-1" {
+		t.Errorf("unexpected auth after refresh: %v", auth.Data)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.refreshes != 1 {
+		t.Errorf("expected one refresh, got %d", m.refreshes)
+	}
+}
+
+func TestResourceMetadataURL(t *testing.T) {
+	got := ResourceMetadataURL(`Bearer error="invalid_token", resource_metadata="https://example.com/.well-known/oauth-protected-resource"`)
+	if got != "https://example.com/.well-known/oauth-protected-resource" {
+		t.Errorf("unexpected url: %q", got)
+	}
+	if ResourceMetadataURL(`Basic realm="x"`) != "" {
+		t.Error("expected no url")
+	}
+}
+
+func TestWellKnown(t *testing.T) {
+	got := wellKnown("https://auth.example.com/tenant/", "oauth-authorization-server")
+	want := []string{
+		"https://auth.example.com/.well-known/oauth-authorization-server/tenant",
+		"https://auth.example.com/.well-known/oauth-authorization-server",
+	}
+	if len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
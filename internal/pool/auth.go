@@ -0,0 +1,187 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caffeinum/mcpfs/internal/config"
+	"github.com/caffeinum/mcpfs/internal/mcp"
+	"github.com/caffeinum/mcpfs/internal/oauth"
+)
+
+// used when a server doesn't set authErrors
+var defaultAuthErrors = []string{
+	`(?i)\bunauthori[sz]ed\b`,
+	`(?i)invalid[_ ](access[_ ])?token`,
+	`(?i)token (has )?expired`,
+	`(?i)authentication (failed|required)`,
+	`(?i)bad credentials`,
+}
+
+const refreshTimeout = 30 * time.Second
+
+// isAuthFailure decides whether an error, or a tool result flagged isError,
+// means the server's credentials need renewing. over http only a 401 does;
+// a 403 is a permission the token lacks, not an expired one. a tool's own
+// error result only counts if it matches the server's authErrors, since
+// "unauthorized" from a tool usually means the resource, not the token.
+func (p *Pool) isAuthFailure(serverName string, err error, res *mcp.ToolResult) bool {
+	var httpErr *mcp.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusUnauthorized
+	}
+
+	srv, _ := p.cfg.GetServer(serverName)
+	var patterns []string
+	if srv != nil {
+		patterns = srv.AuthErrors
+	}
+
+	var text string
+	switch {
+	case err != nil:
+		text = err.Error()
+		if len(patterns) == 0 {
+			patterns = defaultAuthErrors
+		}
+	case res != nil && res.IsError:
+		for _, block := range res.Content {
+			text += block.Text + "\n"
+		}
+	default:
+		return false
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// a line of refreshCommand output that sets a key, as opposed to a token
+// that happens to contain "=", like base64 padding
+var authLinePattern = regexp.MustCompile(`^\w+=[^=]`)
+
+// isBareToken reports whether refreshCommand printed just the token.
+func isBareToken(text string) bool {
+	return !strings.HasPrefix(text, "{") && !strings.Contains(text, "\n") && !authLinePattern.MatchString(text)
+}
+
+// isStartupAuthFailure is isAuthFailure for a failed connect, which also
+// looks at what the server wrote to stderr while starting: stdio servers
+// tend to exit with the reason there and only a closed pipe to show for it.
+func (p *Pool) isStartupAuthFailure(serverName string, err error, logMark int64) bool {
+	stderr := p.logFor(serverName).since(logMark)
+	return p.isAuthFailure(serverName, fmt.Errorf("%w\n%s", err, stderr), nil)
+}
+
+// refreshAuth renews the server's stored credentials, with its oauth
+// refresh token if there is one, else with its refreshCommand. one refresh
+// per server runs at a time, so a rotated refresh token isn't spent twice.
+func (p *Pool) refreshAuth(ctx context.Context, serverName string) error {
+	mu := p.refreshLock(serverName)
+	mu.Lock()
+	defer mu.Unlock()
+
+	// the stored credentials belong to the saved server it hides
+	if p.cfg.Shadows(serverName) {
+		return fmt.Errorf("no way to refresh credentials for %s", serverName)
+	}
+
+	auth, err := config.LoadAuth(p.cfg.Dir(), serverName)
+	if err != nil {
+		return err
+	}
+
+	if oauth.CanRefresh(auth) {
+		// refresh a resolved copy and write back only what changed, so
+		// references stay references
+		resolved, err := auth.Resolve()
+		if err != nil {
+			return err
+		}
+		before := make(map[string]string, len(resolved.Data))
+		for k, v := range resolved.Data {
+			before[k] = v
+		}
+		flow := &oauth.Flow{}
+		if err := flow.RefreshAuth(ctx, resolved); err != nil {
+			return err
+		}
+		update := make(map[string]string)
+		for k, v := range resolved.Data {
+			if before[k] != v {
+				update[k] = v
+			}
+		}
+		for k := range before {
+			if _, ok := resolved.Data[k]; !ok {
+				update[k] = ""
+			}
+		}
+		return config.SaveAuthUpdate(p.cfg.Dir(), serverName, auth, update)
+	}
+
+	srv, ok := p.cfg.GetServer(serverName)
+	if !ok || srv.RefreshCommand == "" {
+		return fmt.Errorf("no way to refresh credentials for %s", serverName)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", srv.RefreshCommand).Output()
+	if err != nil {
+		return fmt.Errorf("refresh command: %w", err)
+	}
+
+	// the command may have updated whatever the references point at, in
+	// which case it prints nothing and there's nothing to store
+	text := strings.TrimSpace(string(out))
+	if text == "" {
+		return nil
+	}
+	if isBareToken(text) {
+		text = oauth.KeyToken + "=" + text
+	}
+	update, err := config.ParseAuthUpdate([]byte(text))
+	if err != nil {
+		return fmt.Errorf("refresh command output: %w", err)
+	}
+	return config.SaveAuthUpdate(p.cfg.Dir(), serverName, auth, update)
+}
+
+func (p *Pool) refreshLock(serverName string) *sync.Mutex {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	mu, ok := p.refreshing[serverName]
+	if !ok {
+		mu = &sync.Mutex{}
+		p.refreshing[serverName] = mu
+	}
+	return mu
+}
+
+// reauthenticate refreshes credentials and reconnects so the next call
+// uses them.
+func (p *Pool) reauthenticate(ctx context.Context, conn *Connection) error {
+	if err := p.refreshAuth(ctx, conn.Name); err != nil {
+		return err
+	}
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	return p.connect(ctx, conn)
+}
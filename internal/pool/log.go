@@ -0,0 +1,68 @@
+package pool
+
+import "sync"
+
+// stderr kept per server, enough for the last few errors
+const logLimit = 64 * 1024
+
+// logBuffer keeps the tail of a server's stderr across reconnects.
+type logBuffer struct {
+	mu      sync.Mutex
+	data    []byte
+	written int64 // ever, so callers can mark a point and ask what came after
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.data = append(b.data, p...)
+	b.written += int64(len(p))
+	if over := len(b.data) - logLimit; over > 0 {
+		b.data = append([]byte(nil), b.data[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *logBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.data...)
+}
+
+func (b *logBuffer) mark() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.written
+}
+
+// since returns what was written after mark, as far as it is still kept.
+func (b *logBuffer) since(mark int64) []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := min(b.written-mark, int64(len(b.data)))
+	return append([]byte(nil), b.data[int64(len(b.data))-n:]...)
+}
+
+func (p *Pool) logFor(serverName string) *logBuffer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	buf, ok := p.logs[serverName]
+	if !ok {
+		buf = &logBuffer{}
+		p.logs[serverName] = buf
+	}
+	return buf
+}
+
+// Log returns what a stdio server has written to stderr lately.
+func (p *Pool) Log(serverName string) []byte {
+	p.mu.RLock()
+	buf, ok := p.logs[serverName]
+	p.mu.RUnlock()
+	if !ok {
+		return []byte{}
+	}
+	return buf.Bytes()
+}
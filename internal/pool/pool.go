@@ -2,8 +2,14 @@ package pool
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,10 +22,18 @@ type Pool struct {
 	connections map[string]*Connection
 	mu          sync.RWMutex
 	idleTimeout time.Duration
+	elicit      ElicitFunc
+	logs        map[string]*logBuffer  // server -> stderr tail
+	secrets     map[string][]string    // server -> resolved secret values, for redaction
+	refreshing  map[string]*sync.Mutex // server -> held while its credentials are renewed
 	stopChan    chan struct{}
 	wg          sync.WaitGroup
 }
 
+// ElicitFunc answers an elicitation/create request sent by serverName while
+// one of its tools is running.
+type ElicitFunc func(ctx context.Context, serverName string, req *mcp.ElicitRequest) (*mcp.ElicitResult, error)
+
 type Connection struct {
 	Name       string
 	Client     mcp.Client
@@ -27,6 +41,7 @@ type Connection struct {
 	LastAccess time.Time
 	Status     ConnectionStatus
 	Error      error
+	pool       *Pool
 	mu         sync.RWMutex
 }
 
@@ -68,6 +83,9 @@ func New(pcfg PoolConfig) *Pool {
 		cfg:         pcfg.Config,
 		connections: make(map[string]*Connection),
 		idleTimeout: pcfg.IdleTimeout,
+		logs:        make(map[string]*logBuffer),
+		secrets:     make(map[string][]string),
+		refreshing:  make(map[string]*sync.Mutex),
 		stopChan:    make(chan struct{}),
 	}
 
@@ -77,6 +95,14 @@ func New(pcfg PoolConfig) *Pool {
 	return p
 }
 
+// SetElicitHandler routes elicitation requests from servers to fn. it only
+// affects connections made afterwards.
+func (p *Pool) SetElicitHandler(fn ElicitFunc) {
+	p.mu.Lock()
+	p.elicit = fn
+	p.mu.Unlock()
+}
+
 func (p *Pool) GetConnection(ctx context.Context, serverName string) (*Connection, error) {
 	p.mu.Lock()
 	conn, exists := p.connections[serverName]
@@ -98,24 +124,40 @@ func (p *Pool) GetConnection(ctx context.Context, serverName string) (*Connectio
 		return conn, nil
 	}
 
-	if conn.Status == StatusError {
-		conn.Status = StatusDisconnected
-		conn.Error = nil
+	// one retry with fresh credentials if the server turned ours down
+	logMark := p.logFor(serverName).mark()
+	err := p.connect(ctx, conn)
+	if err != nil && p.isStartupAuthFailure(serverName, err, logMark) && p.refreshAuth(ctx, serverName) == nil {
+		err = p.connect(ctx, conn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connect starts a client for conn and fetches its tools. conn.mu must be
+// held.
+func (p *Pool) connect(ctx context.Context, conn *Connection) error {
+	if conn.Client != nil {
+		conn.Client.Close()
+		conn.Client = nil
 	}
 
 	conn.Status = StatusConnecting
-	client, err := p.createClient(serverName)
+	conn.Error = nil
+	client, err := p.createClient(conn.Name)
 	if err != nil {
 		conn.Status = StatusError
 		conn.Error = err
-		return nil, err
+		return err
 	}
 
 	if err := client.Initialize(ctx); err != nil {
 		client.Close()
 		conn.Status = StatusError
 		conn.Error = err
-		return nil, fmt.Errorf("initialize: %w", err)
+		return fmt.Errorf("initialize: %w", err)
 	}
 
 	tools, err := client.ListTools(ctx)
@@ -123,15 +165,15 @@ func (p *Pool) GetConnection(ctx context.Context, serverName string) (*Connectio
 		client.Close()
 		conn.Status = StatusError
 		conn.Error = err
-		return nil, fmt.Errorf("list tools: %w", err)
+		return fmt.Errorf("list tools: %w", err)
 	}
 
 	conn.Client = client
 	conn.Tools = tools
 	conn.Status = StatusConnected
-	conn.Error = nil
+	conn.pool = p
 
-	return conn, nil
+	return nil
 }
 
 func (p *Pool) createClient(serverName string) (mcp.Client, error) {
@@ -140,24 +182,43 @@ func (p *Pool) createClient(serverName string) (mcp.Client, error) {
 		return nil, fmt.Errorf("server not found: %s", serverName)
 	}
 
-	auth, _ := config.LoadAuth(p.cfg.Dir(), serverName)
+	// a project server standing in for a saved one doesn't get its auth;
+	// its ${auth.*} references stay unresolved
+	var auth *config.Auth
+	if !p.cfg.Shadows(serverName) {
+		var err error
+		if auth, err = config.LoadAuth(p.cfg.Dir(), serverName); err != nil {
+			return nil, err
+		}
+	}
+	resolvedEnv, headers, secrets, err := srv.ResolveSecrets(auth)
+	if err != nil {
+		return nil, fmt.Errorf("resolve secrets: %w", err)
+	}
+	p.mu.Lock()
+	p.secrets[serverName] = secrets
+	p.mu.Unlock()
+	handlers := p.handlers(serverName)
 
 	switch srv.Transport {
 	case config.TransportStdio:
 		env := os.Environ()
-		for k, v := range srv.ResolveEnv(auth) {
+		for k, v := range resolvedEnv {
 			env = append(env, k+"="+v)
 		}
 		return mcp.NewStdioClient(mcp.StdioConfig{
-			Command: srv.Command,
-			Args:    srv.Args,
-			Env:     env,
+			Command:  srv.Command,
+			Args:     srv.Args,
+			Env:      env,
+			Stderr:   p.logFor(serverName),
+			Handlers: handlers,
 		})
 
 	case config.TransportHTTP:
 		return mcp.NewHTTPClient(mcp.HTTPConfig{
-			URL:     srv.URL,
-			Headers: srv.ResolveHeaders(auth),
+			URL:      srv.URL,
+			Headers:  headers,
+			Handlers: handlers,
 		}), nil
 
 	default:
@@ -165,6 +226,79 @@ func (p *Pool) createClient(serverName string) (mcp.Client, error) {
 	}
 }
 
+// Secrets returns a copy of the secret values the server was last started
+// with, which callers are free to append to.
+func (p *Pool) Secrets(serverName string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return slices.Clone(p.secrets[serverName])
+}
+
+func (p *Pool) handlers(serverName string) mcp.Handlers {
+	p.mu.RLock()
+	elicit := p.elicit
+	p.mu.RUnlock()
+
+	h := mcp.Handlers{
+		Roots: func(ctx context.Context) ([]mcp.Root, error) {
+			return p.roots(serverName), nil
+		},
+	}
+	if elicit != nil {
+		h.Elicit = func(ctx context.Context, req *mcp.ElicitRequest) (*mcp.ElicitResult, error) {
+			return elicit(ctx, serverName, req)
+		}
+	}
+	return h
+}
+
+// roots reads the configured directories on every request so edits are
+// visible without reconnecting.
+func (p *Pool) roots(serverName string) []mcp.Root {
+	srv, ok := p.cfg.GetServer(serverName)
+	if !ok {
+		return nil
+	}
+
+	home, _ := os.UserHomeDir()
+	var roots []mcp.Root
+	for _, dir := range srv.Roots {
+		if dir == "~" || strings.HasPrefix(dir, "~/") {
+			dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		u := url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+		roots = append(roots, mcp.Root{
+			URI:  u.String(),
+			Name: filepath.Base(abs),
+		})
+	}
+	return roots
+}
+
+// NotifyRootsChanged tells a connected server to fetch roots/list again.
+// servers that aren't running pick up the new roots when they connect.
+func (p *Pool) NotifyRootsChanged(ctx context.Context, serverName string) error {
+	p.mu.RLock()
+	conn, exists := p.connections[serverName]
+	p.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+
+	conn.mu.RLock()
+	client := conn.Client
+	conn.mu.RUnlock()
+	if client == nil {
+		return nil
+	}
+
+	return client.Notify(ctx, "notifications/roots/list_changed", nil)
+}
+
 func (p *Pool) GetStatus() map[string]*ConnectionInfo {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
@@ -278,7 +412,35 @@ func (c *Connection) GetTools() []mcp.Tool {
 	return c.Tools
 }
 
+// CallTool calls the tool, refreshing credentials and reconnecting once if
+// the server rejects them, either as an error or an isError result. the call
+// is only made again if it can't have done anything the first time: the
+// server turned it away with a 401, or the tool is read-only.
 func (c *Connection) CallTool(ctx context.Context, name string, args map[string]any) (*mcp.ToolResult, error) {
+	res, err := c.callTool(ctx, name, args)
+	if c.pool != nil && c.pool.isAuthFailure(c.Name, err, res) && (isUnauthorized(err) || c.readOnly(name)) {
+		if rerr := c.pool.reauthenticate(ctx, c); rerr == nil {
+			return c.callTool(ctx, name, args)
+		}
+	}
+	return res, err
+}
+
+func (c *Connection) readOnly(name string) bool {
+	for _, tool := range c.GetTools() {
+		if tool.Name == name {
+			return tool.ReadOnly()
+		}
+	}
+	return false
+}
+
+func isUnauthorized(err error) bool {
+	var httpErr *mcp.HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized
+}
+
+func (c *Connection) callTool(ctx context.Context, name string, args map[string]any) (*mcp.ToolResult, error) {
 	c.mu.Lock()
 	c.LastAccess = time.Now()
 	client := c.Client
@@ -290,3 +452,26 @@ func (c *Connection) CallTool(ctx context.Context, name string, args map[string]
 
 	return client.CallTool(ctx, name, args)
 }
+
+func (c *Connection) Complete(ctx context.Context, ref mcp.CompletionRef, arg mcp.CompletionArgument) (*mcp.Completion, error) {
+	c.mu.Lock()
+	c.LastAccess = time.Now()
+	client := c.Client
+	c.mu.Unlock()
+
+	if client == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+
+	return client.Complete(ctx, ref, arg)
+}
+
+// Capabilities is what the server announced when it connected.
+func (c *Connection) Capabilities() mcp.ServerCapabilities {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Client == nil {
+		return mcp.ServerCapabilities{}
+	}
+	return c.Client.Capabilities()
+}
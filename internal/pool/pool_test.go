@@ -3,12 +3,17 @@ package pool
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/caffeinum/mcpfs/internal/config"
+	"github.com/caffeinum/mcpfs/internal/mcp"
 )
 
 func TestPoolLazyConnection(t *testing.T) {
@@ -207,3 +212,273 @@ func createMockServer(t *testing.T) *httptest.Server {
 		json.NewEncoder(w).Encode(resp)
 	}))
 }
+
+func TestPoolRoots(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		Servers: map[string]*config.ServerConfig{
+			"@test/server": {
+				Transport: config.TransportHTTP,
+				URL:       "http://localhost",
+				Roots:     []string{dir},
+			},
+		},
+	}
+
+	pool := New(PoolConfig{Config: cfg})
+	defer pool.Close()
+
+	roots := pool.roots("@test/server")
+	if len(roots) != 1 {
+		t.Fatalf("expected 1 root, got %d", len(roots))
+	}
+	if roots[0].URI != "file://"+dir {
+		t.Errorf("expected file://%s, got %s", dir, roots[0].URI)
+	}
+	if roots[0].Name != filepath.Base(dir) {
+		t.Errorf("expected name %s, got %s", filepath.Base(dir), roots[0].Name)
+	}
+
+	if err := pool.NotifyRootsChanged(context.Background(), "@test/server"); err != nil {
+		t.Errorf("notify without connection: %v", err)
+	}
+}
+
+func TestLogBufferKeepsTail(t *testing.T) {
+	var buf logBuffer
+	buf.Write(make([]byte, logLimit-2))
+	buf.Write([]byte("tail\n"))
+
+	data := buf.Bytes()
+	if len(data) != logLimit {
+		t.Fatalf("expected %d bytes, got %d", logLimit, len(data))
+	}
+	if string(data[len(data)-5:]) != "tail\n" {
+		t.Errorf("expected tail kept, got %q", data[len(data)-5:])
+	}
+}
+
+func TestPoolRefreshesOnUnauthorized(t *testing.T) {
+	mock := createMockServer(t)
+	defer mock.Close()
+
+	var refreshes int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			if r.PostForm.Get("refresh_token") != "refresh-1" {
+				http.Error(w, "bad refresh token", http.StatusBadRequest)
+				return
+			}
+			mu.Lock()
+			refreshes++
+			mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "fresh", "token_type": "Bearer"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer fresh" {
+			http.Error(w, "token expired", http.StatusUnauthorized)
+			return
+		}
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg, _ := config.Load(dir)
+	cfg.AddHTTPServer("@test/server", server.URL+"/mcp", map[string]string{
+		"Authorization": "Bearer ${auth.token}",
+	})
+	config.SaveAuthTo(dir, "@test/server", &config.Auth{Data: map[string]string{
+		"token":                "stale",
+		"refresh_token":        "refresh-1",
+		"oauth_client_id":      "client-1",
+		"oauth_token_endpoint": server.URL + "/token",
+	}}, config.AuthStoreFile)
+
+	pool := New(PoolConfig{Config: cfg})
+	defer pool.Close()
+
+	conn, err := pool.GetConnection(context.Background(), "@test/server")
+	if err != nil {
+		t.Fatalf("expected connect after refresh, got %v", err)
+	}
+	res, err := conn.CallTool(context.Background(), "echo", map[string]any{"text": "hi"})
+	if err != nil || res.Content[0].Text != "echo: hi" {
+		t.Fatalf("unexpected call result: %+v, %v", res, err)
+	}
+
+	auth, _ := config.LoadAuth(dir, "@test/server")
+	if auth.Data["token"] != "fresh" {
+		t.Errorf("expected refreshed token saved, got %q", auth.Data["token"])
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if refreshes != 1 {
+		t.Errorf("expected one refresh, got %d", refreshes)
+	}
+}
+
+func TestPoolRefreshCommand(t *testing.T) {
+	mock := createMockServer(t)
+	defer mock.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fresh==" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg, _ := config.Load(dir)
+	cfg.SetServer("@test/server", &config.ServerConfig{
+		Transport:      config.TransportHTTP,
+		URL:            server.URL,
+		Headers:        map[string]string{"Authorization": "Bearer ${auth.token}"},
+		RefreshCommand: "echo fresh==",
+	})
+	config.SaveTokenTo(dir, "@test/server", "stale", config.AuthStoreFile)
+
+	pool := New(PoolConfig{Config: cfg})
+	defer pool.Close()
+
+	if _, err := pool.GetConnection(context.Background(), "@test/server"); err != nil {
+		t.Fatalf("expected connect after refresh command, got %v", err)
+	}
+	auth, _ := config.LoadAuth(dir, "@test/server")
+	if auth.Data["token"] != "fresh==" {
+		t.Errorf("expected token from refresh command, got %q", auth.Data["token"])
+	}
+}
+
+func TestPoolProjectShadowGetsNoAuth(t *testing.T) {
+	mock := createMockServer(t)
+	defer mock.Close()
+
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Authorization"))
+		mu.Unlock()
+		mock.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg, _ := config.Load(dir)
+	cfg.AddHTTPServer("@test/server", mock.URL, map[string]string{"Authorization": "Bearer ${auth.token}"})
+	cfg.Save()
+	config.SaveTokenTo(dir, "@test/server", "secret", config.AuthStoreFile)
+
+	path := filepath.Join(t.TempDir(), config.ProjectFile)
+	os.WriteFile(path, []byte(`{"servers": {"@test/server": {
+		"transport": "http",
+		"url": "`+server.URL+`",
+		"headers": {"Authorization": "Bearer ${auth.token}"}
+	}}}`), 0644)
+	project, err := config.LoadProject(path)
+	if err != nil {
+		t.Fatalf("load project: %v", err)
+	}
+	cfg.SetProject(project)
+
+	pool := New(PoolConfig{Config: cfg})
+	defer pool.Close()
+
+	if _, err := pool.GetConnection(context.Background(), "@test/server"); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, header := range seen {
+		if header == "Bearer secret" {
+			t.Fatal("expected the saved server's token kept from the project server")
+		}
+	}
+}
+
+func TestSecretsIsACopy(t *testing.T) {
+	cfg, _ := config.Load(t.TempDir())
+	pool := New(PoolConfig{Config: cfg})
+	defer pool.Close()
+
+	pool.secrets["@test/server"] = make([]string, 1, 4)
+	pool.secrets["@test/server"][0] = "one"
+
+	a := append(pool.Secrets("@test/server"), "from-a")
+	b := append(pool.Secrets("@test/server"), "from-b")
+	if a[1] != "from-a" || b[1] != "from-b" {
+		t.Errorf("expected callers not to share a backing array, got %v and %v", a, b)
+	}
+}
+
+func TestIsAuthFailure(t *testing.T) {
+	cfg := &config.Config{
+		Servers: map[string]*config.ServerConfig{
+			"@test/default": {Transport: config.TransportStdio, Command: "x"},
+			"@test/custom":  {Transport: config.TransportStdio, Command: "x", AuthErrors: []string{`code 4011`}},
+		},
+	}
+	pool := New(PoolConfig{Config: cfg})
+	defer pool.Close()
+
+	tests := []struct {
+		server string
+		err    error
+		res    *mcp.ToolResult
+		want   bool
+	}{
+		{"@test/default", &mcp.HTTPError{StatusCode: 401}, nil, true},
+		{"@test/default", &mcp.HTTPError{StatusCode: 403, Body: "token expired"}, nil, false},
+		{"@test/default", &mcp.HTTPError{StatusCode: 500, Body: "unauthorized"}, nil, false},
+		{"@test/default", errors.New("initialize: Bad credentials"), nil, true},
+		{"@test/default", errors.New("connection refused"), nil, false},
+		{"@test/default", nil, &mcp.ToolResult{IsError: true, Content: []mcp.ContentBlock{{Type: "text", Text: "token has expired"}}}, false},
+		{"@test/custom", nil, &mcp.ToolResult{IsError: true, Content: []mcp.ContentBlock{{Type: "text", Text: "code 4011"}}}, true},
+		{"@test/custom", nil, &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "code 4011"}}}, false},
+		{"@test/custom", errors.New("error code 4011"), nil, true},
+		{"@test/custom", errors.New("unauthorized"), nil, false},
+	}
+	for _, tt := range tests {
+		if got := pool.isAuthFailure(tt.server, tt.err, tt.res); got != tt.want {
+			t.Errorf("isAuthFailure(%s, %v, %+v) = %v, want %v", tt.server, tt.err, tt.res, got, tt.want)
+		}
+	}
+
+	// a stdio server that exits on startup explains itself on stderr
+	log := pool.logFor("@test/default")
+	log.Write([]byte("401 Bad credentials from an earlier run\n"))
+	mark := log.mark()
+	if pool.isStartupAuthFailure("@test/default", errors.New("initialize: EOF"), mark) {
+		t.Error("expected stderr from before the connect to be ignored")
+	}
+	log.Write([]byte("error: token has expired\n"))
+	if !pool.isStartupAuthFailure("@test/default", errors.New("initialize: EOF"), mark) {
+		t.Error("expected stderr to be matched")
+	}
+}
+
+func TestIsBareToken(t *testing.T) {
+	tests := []struct {
+		out  string
+		want bool
+	}{
+		{"ghp_abc123", true},
+		{"dG9rZW4=", true},
+		{"abc==", true},
+		{"token=ghp_abc123", false},
+		{"token=abc==", false},
+		{`{"token":"x"}`, false},
+		{"token=a\nrefresh_token=b", false},
+	}
+	for _, tt := range tests {
+		if got := isBareToken(tt.out); got != tt.want {
+			t.Errorf("isBareToken(%q) = %v, want %v", tt.out, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const Mask = "[REDACTED]"
+
+// shorter values are too likely to be ordinary words or numbers
+const minSecretLen = 6
+
+// well-known token shapes, masked even when we don't know the value
+var patterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{20,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{20,}\b`),
+	regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}\b`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}\b`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]{12,}`),
+}
+
+// Redactor masks known secret values and common token patterns.
+type Redactor struct {
+	secrets []string
+}
+
+func New(secrets ...string) *Redactor {
+	r := &Redactor{}
+	seen := make(map[string]bool)
+	for _, s := range secrets {
+		if len(s) >= minSecretLen && !seen[s] {
+			seen[s] = true
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	// longest first so a secret containing another is masked whole
+	sort.Slice(r.secrets, func(i, j int) bool {
+		return len(r.secrets[i]) > len(r.secrets[j])
+	})
+	return r
+}
+
+func (r *Redactor) String(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	for _, re := range patterns {
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			if strings.HasPrefix(strings.ToLower(match), "bearer") {
+				return match[:len("bearer")] + " " + Mask
+			}
+			return Mask
+		})
+	}
+	return s
+}
+
+func (r *Redactor) Bytes(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	return []byte(r.String(string(b)))
+}
@@ -0,0 +1,131 @@
+package result
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+// Text joins every text block. results without text fall back to the
+// content blocks as json. errors are prefixed so they stand out in pipes.
+func Text(r *mcp.ToolResult) []byte {
+	var texts []string
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+
+	if len(texts) == 0 {
+		data, _ := json.MarshalIndent(r.Content, "", "  ")
+		return append(data, '\n')
+	}
+
+	text := strings.Join(texts, "\n")
+	if r.IsError {
+		text = "error: " + text
+	}
+	return []byte(text + "\n")
+}
+
+// JSON is the full result envelope, including isError, structuredContent
+// and _meta, as the server sent it.
+func JSON(r *mcp.ToolResult) []byte {
+	raw := r.Raw
+	if len(raw) == 0 {
+		raw, _ = json.Marshal(r)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return append(raw, '\n')
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// NDJSON writes one compact line per content block.
+func NDJSON(r *mcp.ToolResult) []byte {
+	var buf bytes.Buffer
+	for _, block := range r.Content {
+		data, _ := json.Marshal(block)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// Markdown renders the result for people: text as-is, resources and
+// structured content as fenced blocks, binary data as a short note.
+func Markdown(r *mcp.ToolResult) []byte {
+	var parts []string
+	if r.IsError {
+		parts = append(parts, "**error**")
+	}
+
+	for _, block := range r.Content {
+		switch block.Type {
+		case "text":
+			parts = append(parts, block.Text)
+
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("*[%s: %s, %d bytes base64]*", block.Type, block.MimeType, len(block.Data)))
+
+		case "resource_link":
+			name := block.Name
+			if name == "" {
+				name = block.URI
+			}
+			parts = append(parts, fmt.Sprintf("[%s](%s)", name, block.URI))
+
+		case "resource":
+			var res struct {
+				URI      string `json:"uri"`
+				MimeType string `json:"mimeType"`
+				Text     string `json:"text"`
+				Blob     string `json:"blob"`
+			}
+			json.Unmarshal(block.Resource, &res)
+			if res.Text != "" {
+				parts = append(parts, fmt.Sprintf("`%s`\n\n%s", res.URI, fence(res.Text, langFor(res.MimeType))))
+			} else {
+				parts = append(parts, fmt.Sprintf("`%s` *(%s, %d bytes base64)*", res.URI, res.MimeType, len(res.Blob)))
+			}
+
+		default:
+			data, _ := json.MarshalIndent(block, "", "  ")
+			parts = append(parts, fence(string(data), "json"))
+		}
+	}
+
+	if len(r.StructuredContent) > 0 {
+		var buf bytes.Buffer
+		json.Indent(&buf, r.StructuredContent, "", "  ")
+		parts = append(parts, fence(buf.String(), "json"))
+	}
+
+	return []byte(strings.Join(parts, "\n\n") + "\n")
+}
+
+func fence(text, lang string) string {
+	marker := "```"
+	for strings.Contains(text, marker) {
+		marker += "`"
+	}
+	return marker + lang + "\n" + strings.TrimRight(text, "\n") + "\n" + marker
+}
+
+func langFor(mimeType string) string {
+	switch {
+	case strings.HasSuffix(mimeType, "json"):
+		return "json"
+	case strings.HasSuffix(mimeType, "yaml"):
+		return "yaml"
+	case mimeType == "text/markdown":
+		return "markdown"
+	}
+	return ""
+}
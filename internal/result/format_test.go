@@ -0,0 +1,96 @@
+package result
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+func TestText(t *testing.T) {
+	r := &mcp.ToolResult{Content: []mcp.ContentBlock{
+		{Type: "text", Text: "first"},
+		{Type: "image", Data: "aGk=", MimeType: "image/png"},
+		{Type: "text", Text: "second"},
+	}}
+	if got := string(Text(r)); got != "first\nsecond\n" {
+		t.Errorf("unexpected text: %q", got)
+	}
+
+	r.IsError = true
+	if got := string(Text(r)); got != "error: first\nsecond\n" {
+		t.Errorf("unexpected error text: %q", got)
+	}
+
+	noText := &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "image", Data: "aGk="}}}
+	if got := string(Text(noText)); !strings.Contains(got, `"data": "aGk="`) {
+		t.Errorf("expected json fallback, got %q", got)
+	}
+}
+
+func TestJSONKeepsEnvelope(t *testing.T) {
+	raw := json.RawMessage(`{"content":[{"type":"text","text":"hi"}],"isError":false,"_meta":{"trace":"abc"}}`)
+	r := &mcp.ToolResult{
+		Content: []mcp.ContentBlock{{Type: "text", Text: "hi"}},
+		Meta:    json.RawMessage(`{"trace":"abc"}`),
+		Raw:     raw,
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(JSON(r), &got); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if _, ok := got["isError"]; !ok {
+		t.Error("expected isError in envelope")
+	}
+	if got["_meta"].(map[string]any)["trace"] != "abc" {
+		t.Errorf("expected _meta, got %v", got["_meta"])
+	}
+
+	// synthetic results have no raw form
+	synthetic := &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "boom"}}, IsError: true}
+	if !strings.Contains(string(JSON(synthetic)), `"isError": true`) {
+		t.Errorf("unexpected json: %s", JSON(synthetic))
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	r := &mcp.ToolResult{Content: []mcp.ContentBlock{
+		{Type: "text", Text: "a\nb"},
+		{Type: "text", Text: "c"},
+	}}
+	lines := strings.Split(strings.TrimSuffix(string(NDJSON(r)), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", lines)
+	}
+	var block mcp.ContentBlock
+	if err := json.Unmarshal([]byte(lines[0]), &block); err != nil || block.Text != "a\nb" {
+		t.Errorf("unexpected first line: %s", lines[0])
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	r := &mcp.ToolResult{
+		Content: []mcp.ContentBlock{
+			{Type: "text", Text: "found 1 repo"},
+			{Type: "resource_link", URI: "https://github.com/caffeinum/mcpfs", Name: "mcpfs"},
+			{Type: "image", Data: "aGk=", MimeType: "image/png"},
+			{Type: "resource", Resource: json.RawMessage(`{"uri":"file:///a.json","mimeType":"application/json","text":"{}"}`)},
+		},
+		StructuredContent: json.RawMessage(`{"count":1}`),
+	}
+
+	md := string(Markdown(r))
+	for _, want := range []string{
+		"found 1 repo",
+		"[mcpfs](https://github.com/caffeinum/mcpfs)",
+		"*[image: image/png, 4 bytes base64]*",
+		"`file:///a.json`\n\n```json\n{}\n```",
+		"```json\n{\n  \"count\": 1\n}\n```",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
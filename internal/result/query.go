@@ -0,0 +1,698 @@
+package result
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+// Query is a small jq-like filter, so results can be projected in sandboxes
+// without jq. it understands paths (.a.b, .["k"], [0], [-1], [1:3], [] and
+// JSONPath's $ and [*]), pipes, map(f), select(f op literal), keys, length,
+// first, last, type and contains(literal).
+type Query struct {
+	expr   string
+	stages []stage
+}
+
+// a stage maps one input value to any number of outputs
+type stage func(v any) ([]any, error)
+
+func ParseQuery(expr string) (*Query, error) {
+	p := &queryParser{src: strings.TrimSpace(expr)}
+	stages, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.done() {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return &Query{expr: expr, stages: stages}, nil
+}
+
+func (q *Query) String() string {
+	return q.expr
+}
+
+func (q *Query) Eval(v any) ([]any, error) {
+	return run(q.stages, v)
+}
+
+func run(stages []stage, v any) ([]any, error) {
+	values := []any{v}
+	for _, st := range stages {
+		var next []any
+		for _, in := range values {
+			out, err := st(in)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+// Document is what a query runs against: structuredContent when the server
+// sent it, otherwise the text view parsed as json.
+func Document(r *mcp.ToolResult) (any, error) {
+	var doc any
+	if len(r.StructuredContent) > 0 {
+		if err := json.Unmarshal(r.StructuredContent, &doc); err == nil {
+			return doc, nil
+		}
+	}
+
+	var texts []string
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			texts = append(texts, block.Text)
+		}
+	}
+	if err := json.Unmarshal([]byte(strings.Join(texts, "\n")), &doc); err != nil {
+		return nil, fmt.Errorf("result is not json: %w", err)
+	}
+	return doc, nil
+}
+
+// Project runs q against the result and prints one output per line.
+// strings print raw, like jq -r, so they pipe cleanly into other tools.
+func Project(r *mcp.ToolResult, q *Query) ([]byte, error) {
+	doc, err := Document(r)
+	if err != nil {
+		return nil, err
+	}
+	values, err := q.Eval(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			buf.WriteString(s)
+		} else {
+			data, _ := json.MarshalIndent(v, "", "  ")
+			buf.Write(data)
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+type queryParser struct {
+	src string
+	pos int
+}
+
+func (p *queryParser) done() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *queryParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *queryParser) skipSpace() {
+	for !p.done() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *queryParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("query: "+format+" at position %d", append(args, p.pos)...)
+}
+
+func (p *queryParser) parsePipe() ([]stage, error) {
+	var stages []stage
+	for {
+		p.skipSpace()
+		st, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, st...)
+
+		p.skipSpace()
+		if p.peek() != '|' {
+			return stages, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *queryParser) parseTerm() ([]stage, error) {
+	switch c := p.peek(); {
+	case c == '.' || c == '$' || c == '[':
+		return p.parsePath()
+	case isIdentStart(c):
+		return p.parseFunc()
+	case c == 0:
+		return nil, p.errorf("unexpected end of query")
+	default:
+		return nil, p.errorf("unexpected %q", string(c))
+	}
+}
+
+func (p *queryParser) parsePath() ([]stage, error) {
+	var stages []stage
+
+	if p.peek() == '$' {
+		p.pos++
+	}
+	if p.peek() == '.' {
+		p.pos++
+		switch c := p.peek(); {
+		case isIdentStart(c):
+			stages = append(stages, keyStage(p.ident()))
+		case c == '"':
+			key, err := p.quoted()
+			if err != nil {
+				return nil, err
+			}
+			stages = append(stages, keyStage(key))
+		}
+	}
+
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			switch c := p.peek(); {
+			case isIdentStart(c):
+				stages = append(stages, keyStage(p.ident()))
+			case c == '"':
+				key, err := p.quoted()
+				if err != nil {
+					return nil, err
+				}
+				stages = append(stages, keyStage(key))
+			case c == '[':
+				// .[0] is the same as [0]
+			default:
+				return nil, p.errorf("expected key after '.'")
+			}
+		case '[':
+			st, err := p.parseBracket()
+			if err != nil {
+				return nil, err
+			}
+			stages = append(stages, st)
+		default:
+			return stages, nil
+		}
+	}
+}
+
+func (p *queryParser) parseBracket() (stage, error) {
+	p.pos++ // [
+	p.skipSpace()
+
+	switch c := p.peek(); {
+	case c == ']':
+		p.pos++
+		return iterStage, nil
+
+	case c == '*':
+		p.pos++
+		if err := p.expect(']'); err != nil {
+			return nil, err
+		}
+		return iterStage, nil
+
+	case c == '"' || c == '\'':
+		key, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(']'); err != nil {
+			return nil, err
+		}
+		return keyStage(key), nil
+	}
+
+	start, hasStart := p.number()
+	p.skipSpace()
+	if p.peek() == ':' {
+		p.pos++
+		p.skipSpace()
+		end, hasEnd := p.number()
+		if err := p.expect(']'); err != nil {
+			return nil, err
+		}
+		return sliceStage(start, hasStart, end, hasEnd), nil
+	}
+	if !hasStart {
+		return nil, p.errorf("expected index")
+	}
+	if err := p.expect(']'); err != nil {
+		return nil, err
+	}
+	return indexStage(start), nil
+}
+
+func (p *queryParser) parseFunc() ([]stage, error) {
+	name := p.ident()
+
+	switch name {
+	case "keys", "length", "first", "last", "type":
+		return []stage{builtinStage(name)}, nil
+
+	case "map":
+		inner, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		return []stage{func(v any) ([]any, error) {
+			items, err := iterate(v)
+			if err != nil {
+				return nil, err
+			}
+			out := []any{}
+			for _, item := range items {
+				mapped, err := run(inner, item)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, mapped...)
+			}
+			return []any{out}, nil
+		}}, nil
+
+	case "select":
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		cond, err := p.parseCond()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return []stage{func(v any) ([]any, error) {
+			ok, err := cond(v)
+			if err != nil || !ok {
+				return nil, err
+			}
+			return []any{v}, nil
+		}}, nil
+
+	case "contains":
+		if err := p.expect('('); err != nil {
+			return nil, err
+		}
+		lit, err := p.literal()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')'); err != nil {
+			return nil, err
+		}
+		return []stage{func(v any) ([]any, error) {
+			return []any{contains(v, lit)}, nil
+		}}, nil
+	}
+
+	return nil, p.errorf("unknown function %q", name)
+}
+
+func (p *queryParser) parseArgs() ([]stage, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	inner, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(')'); err != nil {
+		return nil, err
+	}
+	return inner, nil
+}
+
+// parseCond reads `f`, or `f op literal` with op one of == != < <= > >=.
+func (p *queryParser) parseCond() (func(v any) (bool, error), error) {
+	left, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+
+	op := ""
+	for _, candidate := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(p.src[p.pos:], candidate) {
+			op = candidate
+			p.pos += len(candidate)
+			break
+		}
+	}
+
+	var right any
+	if op != "" {
+		p.skipSpace()
+		if right, err = p.literal(); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(v any) (bool, error) {
+		values, err := run(left, v)
+		if err != nil {
+			return false, err
+		}
+		for _, got := range values {
+			if op == "" && truthy(got) || op != "" && compare(got, op, right) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}, nil
+}
+
+func (p *queryParser) literal() (any, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.peek() == '"' {
+		if _, err := p.quoted(); err != nil {
+			return nil, err
+		}
+	} else {
+		for !p.done() && !strings.ContainsRune(" \t)|", rune(p.peek())) {
+			p.pos++
+		}
+	}
+
+	var v any
+	if err := json.Unmarshal([]byte(p.src[start:p.pos]), &v); err != nil {
+		return nil, p.errorf("invalid literal %q", p.src[start:p.pos])
+	}
+	return v, nil
+}
+
+func (p *queryParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return p.errorf("expected %q", string(c))
+	}
+	p.pos++
+	return nil
+}
+
+func (p *queryParser) ident() string {
+	start := p.pos
+	for !p.done() && isIdentChar(p.peek()) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *queryParser) quoted() (string, error) {
+	quote := p.peek()
+	start := p.pos
+	p.pos++
+	for !p.done() && p.peek() != quote {
+		if p.peek() == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.done() {
+		return "", p.errorf("unterminated string")
+	}
+	p.pos++
+
+	raw := p.src[start:p.pos]
+	if quote == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	s, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", p.errorf("invalid string %s", raw)
+	}
+	return s, nil
+}
+
+func (p *queryParser) number() (int, bool) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for !p.done() && p.peek() >= '0' && p.peek() <= '9' {
+		p.pos++
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return n, true
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9' || c == '-'
+}
+
+func keyStage(key string) stage {
+	return func(v any) ([]any, error) {
+		switch obj := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{obj[key]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with %q", typeOf(v), key)
+	}
+}
+
+func indexStage(i int) stage {
+	return func(v any) ([]any, error) {
+		switch arr := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			j := i
+			if j < 0 {
+				j += len(arr)
+			}
+			if j < 0 || j >= len(arr) {
+				return []any{nil}, nil
+			}
+			return []any{arr[j]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with a number", typeOf(v))
+	}
+}
+
+func sliceStage(start int, hasStart bool, end int, hasEnd bool) stage {
+	return func(v any) ([]any, error) {
+		arr, ok := v.([]any)
+		if !ok {
+			if v == nil {
+				return []any{nil}, nil
+			}
+			return nil, fmt.Errorf("cannot slice %s", typeOf(v))
+		}
+		from, to := 0, len(arr)
+		if hasStart {
+			from = clampIndex(start, len(arr))
+		}
+		if hasEnd {
+			to = clampIndex(end, len(arr))
+		}
+		if from > to {
+			from = to
+		}
+		return []any{arr[from:to]}, nil
+	}
+}
+
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+func iterStage(v any) ([]any, error) {
+	return iterate(v)
+}
+
+func iterate(v any) ([]any, error) {
+	switch val := v.(type) {
+	case []any:
+		return val, nil
+	case map[string]any:
+		keys := sortedKeys(val)
+		out := make([]any, len(keys))
+		for i, k := range keys {
+			out[i] = val[k]
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("cannot iterate over %s", typeOf(v))
+}
+
+func builtinStage(name string) stage {
+	return func(v any) ([]any, error) {
+		switch name {
+		case "keys":
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s has no keys", typeOf(v))
+			}
+			keys := sortedKeys(obj)
+			out := make([]any, len(keys))
+			for i, k := range keys {
+				out[i] = k
+			}
+			return []any{out}, nil
+
+		case "length":
+			switch val := v.(type) {
+			case nil:
+				return []any{0.0}, nil
+			case string:
+				return []any{float64(len([]rune(val)))}, nil
+			case []any:
+				return []any{float64(len(val))}, nil
+			case map[string]any:
+				return []any{float64(len(val))}, nil
+			}
+			return nil, fmt.Errorf("%s has no length", typeOf(v))
+
+		case "first", "last":
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot take %s of %s", name, typeOf(v))
+			}
+			if len(arr) == 0 {
+				return []any{nil}, nil
+			}
+			if name == "first" {
+				return []any{arr[0]}, nil
+			}
+			return []any{arr[len(arr)-1]}, nil
+
+		case "type":
+			return []any{typeOf(v)}, nil
+		}
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+}
+
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func truthy(v any) bool {
+	return v != nil && v != false
+}
+
+func compare(a any, op string, b any) bool {
+	switch op {
+	case "==":
+		return jsonEqual(a, b)
+	case "!=":
+		return !jsonEqual(a, b)
+	}
+
+	var c int
+	switch x := a.(type) {
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		switch {
+		case x < y:
+			c = -1
+		case x > y:
+			c = 1
+		}
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(x, y)
+	default:
+		return false
+	}
+
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func jsonEqual(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return bytes.Equal(x, y)
+}
+
+func contains(v, lit any) bool {
+	switch val := v.(type) {
+	case string:
+		s, ok := lit.(string)
+		return ok && strings.Contains(val, s)
+	case []any:
+		for _, item := range val {
+			if jsonEqual(item, lit) {
+				return true
+			}
+		}
+	case map[string]any:
+		s, ok := lit.(string)
+		if ok {
+			_, has := val[s]
+			return has
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package result
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+const reposJSON = `{
+	"total_count": 3,
+	"items": [
+		{"name": "mcpfs", "stars": 120, "topics": ["fuse", "mcp"], "owner": {"login": "caffeinum"}},
+		{"name": "other", "stars": 5, "topics": [], "owner": {"login": "someone"}},
+		{"name": "third", "stars": 42, "topics": ["mcp"], "owner": {"login": "caffeinum"}}
+	],
+	"odd key": true
+}`
+
+func evalQuery(t *testing.T, expr string) string {
+	t.Helper()
+	var doc any
+	json.Unmarshal([]byte(reposJSON), &doc)
+
+	q, err := ParseQuery(expr)
+	if err != nil {
+		t.Fatalf("parse %q: %v", expr, err)
+	}
+	values, err := q.Eval(doc)
+	if err != nil {
+		t.Fatalf("eval %q: %v", expr, err)
+	}
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{".total_count", `[3]`},
+		{".items[0].name", `["mcpfs"]`},
+		{".items[-1].name", `["third"]`},
+		{".items[].name", `["mcpfs","other","third"]`},
+		{"$.items[*].owner.login", `["caffeinum","someone","caffeinum"]`},
+		{".items[1:] | map(.name)", `[["other","third"]]`},
+		{".items[:1] | length", `[1]`},
+		{`.["odd key"]`, `[true]`},
+		{`."odd key"`, `[true]`},
+		{".items | length", `[3]`},
+		{".items | map(.stars)", `[[120,5,42]]`},
+		{".items[] | select(.stars > 10) | .name", `["mcpfs","third"]`},
+		{`.items[] | select(.owner.login == "someone") | .name`, `["other"]`},
+		{`.items[] | select(.topics | contains("fuse")) | .name`, `["mcpfs"]`},
+		{".items | first | .name", `["mcpfs"]`},
+		{".items | last | .stars", `[42]`},
+		{".items[0] | keys", `[["name","owner","stars","topics"]]`},
+		{".items[0].missing", `[null]`},
+		{".items[9]", `[null]`},
+		{".total_count | type", `["number"]`},
+		{".", `[` + compactJSON(reposJSON) + `]`},
+	}
+
+	for _, tt := range tests {
+		if got := evalQuery(t, tt.expr); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func compactJSON(s string) string {
+	var v any
+	json.Unmarshal([]byte(s), &v)
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, expr := range []string{".items[", "frobnicate", ".items | select(.a ==)", ".a.", `."unterminated`} {
+		if _, err := ParseQuery(expr); err == nil {
+			t.Errorf("expected parse error for %q", expr)
+		}
+	}
+
+	q, _ := ParseQuery(".total_count.name")
+	var doc any
+	json.Unmarshal([]byte(reposJSON), &doc)
+	if _, err := q.Eval(doc); err == nil {
+		t.Error("expected error indexing a number")
+	}
+}
+
+func TestProject(t *testing.T) {
+	q, _ := ParseQuery(".items[] | select(.stars > 10) | .name")
+
+	r := &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: reposJSON}}}
+	got, err := Project(r, q)
+	if err != nil {
+		t.Fatalf("project: %v", err)
+	}
+	if string(got) != "mcpfs\nthird\n" {
+		t.Errorf("unexpected projection: %q", got)
+	}
+
+	structured := &mcp.ToolResult{
+		Content:           []mcp.ContentBlock{{Type: "text", Text: "3 repos"}},
+		StructuredContent: json.RawMessage(reposJSON),
+	}
+	if got, _ := Project(structured, q); string(got) != "mcpfs\nthird\n" {
+		t.Errorf("expected structured content to be used, got %q", got)
+	}
+
+	notJSON := &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: "plain words"}}}
+	if _, err := Project(notJSON, q); err == nil || !strings.Contains(err.Error(), "not json") {
+		t.Errorf("expected not json error, got %v", err)
+	}
+}
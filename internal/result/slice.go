@@ -0,0 +1,125 @@
+package result
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+// rough average for english text and json; good enough to budget context
+const bytesPerToken = 4
+
+func EstimateTokens(size int) int {
+	return (size + bytesPerToken - 1) / bytesPerToken
+}
+
+// TokensToBytes converts a page size given in tokens to bytes.
+func TokensToBytes(tokens int) int {
+	return tokens * bytesPerToken
+}
+
+// Head returns the first n lines of the text view.
+func Head(r *mcp.ToolResult, n int) []byte {
+	text := Text(r)
+	if n <= 0 {
+		return []byte{}
+	}
+
+	end := 0
+	for i := 0; i < n; i++ {
+		idx := bytes.IndexByte(text[end:], '\n')
+		if idx < 0 {
+			return text
+		}
+		end += idx + 1
+	}
+	return text[:end]
+}
+
+// Pages splits the text view into chunks of at most size bytes, breaking
+// after a newline when one falls in the second half of the chunk and never
+// inside a utf-8 character otherwise.
+func Pages(r *mcp.ToolResult, size int) [][]byte {
+	return split(Text(r), size)
+}
+
+func split(text []byte, size int) [][]byte {
+	if size <= 0 || len(text) <= size {
+		return [][]byte{text}
+	}
+
+	var pages [][]byte
+	for len(text) > 0 {
+		if len(text) <= size {
+			pages = append(pages, text)
+			break
+		}
+		cut := size
+		if idx := bytes.LastIndexByte(text[:size], '\n'); idx >= size/2 {
+			cut = idx + 1
+		} else {
+			// don't split a multi-byte character across pages, unless the
+			// page can't hold even one
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = size
+			}
+		}
+		pages = append(pages, text[:cut])
+		text = text[cut:]
+	}
+	return pages
+}
+
+// PageName formats page i of total so names sort correctly.
+func PageName(i, total int) string {
+	width := len(fmt.Sprint(total - 1))
+	if width < 3 {
+		width = 3
+	}
+	return fmt.Sprintf("%0*d", width, i)
+}
+
+// Summary describes the result without any of its content, so an agent
+// can decide whether to read it whole or page through it.
+func Summary(r *mcp.ToolResult, pageSize int) []byte {
+	text := Text(r)
+	lines := bytes.Count(text, []byte{'\n'})
+	if len(text) > 0 && text[len(text)-1] != '\n' {
+		lines++
+	}
+
+	types := make(map[string]int)
+	var order []string
+	for _, block := range r.Content {
+		if types[block.Type] == 0 {
+			order = append(order, block.Type)
+		}
+		types[block.Type]++
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "bytes: %d\n", len(text))
+	fmt.Fprintf(&b, "lines: %d\n", lines)
+	fmt.Fprintf(&b, "blocks: %d", len(r.Content))
+	for i, t := range order {
+		if i == 0 {
+			b.WriteString(" (")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d %s", types[t], t)
+		if i == len(order)-1 {
+			b.WriteString(")")
+		}
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "tokens: ~%d\n", EstimateTokens(len(text)))
+	fmt.Fprintf(&b, "pages: %d\n", len(split(text, pageSize)))
+	fmt.Fprintf(&b, "error: %t\n", r.IsError)
+	return b.Bytes()
+}
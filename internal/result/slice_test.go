@@ -0,0 +1,109 @@
+package result
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/caffeinum/mcpfs/internal/mcp"
+)
+
+func textResult(text string) *mcp.ToolResult {
+	return &mcp.ToolResult{Content: []mcp.ContentBlock{{Type: "text", Text: text}}}
+}
+
+func TestHead(t *testing.T) {
+	r := textResult("one\ntwo\nthree\nfour")
+
+	if got := string(Head(r, 2)); got != "one\ntwo\n" {
+		t.Errorf("unexpected head: %q", got)
+	}
+	if got := string(Head(r, 10)); got != "one\ntwo\nthree\nfour\n" {
+		t.Errorf("expected whole text, got %q", got)
+	}
+	if got := Head(r, 0); len(got) != 0 {
+		t.Errorf("expected nothing, got %q", got)
+	}
+}
+
+func TestPages(t *testing.T) {
+	r := textResult(strings.Repeat("0123456789\n", 10)) // 110 bytes + newline
+
+	pages := Pages(r, 32)
+	var joined []byte
+	for i, p := range pages {
+		if len(p) > 32 {
+			t.Errorf("page %d is %d bytes", i, len(p))
+		}
+		if i < len(pages)-1 && !bytes.HasSuffix(p, []byte("\n")) {
+			t.Errorf("page %d should end on a line break: %q", i, p)
+		}
+		joined = append(joined, p...)
+	}
+	if !bytes.Equal(joined, Text(r)) {
+		t.Error("pages don't add up to the text")
+	}
+
+	// a single long line still gets cut
+	long := Pages(textResult(strings.Repeat("x", 100)), 40)
+	if len(long) != 3 {
+		t.Errorf("expected 3 pages, got %d", len(long))
+	}
+
+	if n := len(Pages(textResult("short"), 0)); n != 1 {
+		t.Errorf("expected 1 page without a size, got %d", n)
+	}
+}
+
+func TestPagesKeepCharactersWhole(t *testing.T) {
+	text := strings.Repeat("é", 50) // 2 bytes each, no newlines
+	pages := split([]byte(text), 7)
+
+	var joined []byte
+	for i, p := range pages {
+		if len(p) > 7 || !utf8.Valid(p) {
+			t.Errorf("page %d is %d bytes, valid utf-8: %v: %q", i, len(p), utf8.Valid(p), p)
+		}
+		joined = append(joined, p...)
+	}
+	if string(joined) != text {
+		t.Error("pages don't add up to the text")
+	}
+
+	// a page too small for one character still makes progress
+	if pages := split([]byte("€€"), 2); string(bytes.Join(pages, nil)) != "€€" {
+		t.Errorf("unexpected pages: %q", pages)
+	}
+}
+
+func TestPageName(t *testing.T) {
+	if got := PageName(7, 12); got != "007" {
+		t.Errorf("expected 007, got %s", got)
+	}
+	if got := PageName(42, 1500); got != "0042" {
+		t.Errorf("expected 0042, got %s", got)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	r := &mcp.ToolResult{Content: []mcp.ContentBlock{
+		{Type: "text", Text: "line one\nline two"},
+		{Type: "image", Data: "aGk="},
+		{Type: "text", Text: "tail"},
+	}}
+
+	got := string(Summary(r, 10))
+	for _, want := range []string{
+		"bytes: 23\n",
+		"lines: 3\n",
+		"blocks: 3 (2 text, 1 image)\n",
+		"tokens: ~6\n",
+		"pages: 3\n",
+		"error: false\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+}
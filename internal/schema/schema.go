@@ -0,0 +1,213 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is the subset of JSON Schema that tools use for inputSchema.
+type Schema struct {
+	Type                 json.RawMessage    `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties,omitempty"`
+}
+
+func Parse(raw json.RawMessage) (*Schema, error) {
+	s := &Schema{}
+	if len(raw) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(raw, s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	return s, nil
+}
+
+// Types returns the allowed types; "type" may be a string or a list.
+func (s *Schema) Types() []string {
+	if len(s.Type) == 0 {
+		return nil
+	}
+	var one string
+	if err := json.Unmarshal(s.Type, &one); err == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(s.Type, &many)
+	return many
+}
+
+func (s *Schema) PropertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnumValues returns the enum of a property as strings, keeping only the
+// ones that start with prefix.
+func (s *Schema) EnumValues(property, prefix string) []string {
+	prop, ok := s.Properties[property]
+	if !ok {
+		return nil
+	}
+	enum := prop.Enum
+	if len(enum) == 0 && prop.Items != nil {
+		enum = prop.Items.Enum
+	}
+
+	var values []string
+	for _, v := range enum {
+		str, ok := v.(string)
+		if !ok {
+			data, _ := json.Marshal(v)
+			str = string(data)
+		}
+		if strings.HasPrefix(str, prefix) {
+			values = append(values, str)
+		}
+	}
+	return values
+}
+
+// Coerce turns the raw text written to args/<property> into a value of the
+// property's type. a trailing newline from echo is dropped.
+func (s *Schema) Coerce(property, raw string) (any, error) {
+	raw = strings.TrimRight(raw, "\r\n")
+
+	prop, ok := s.Properties[property]
+	if !ok {
+		prop = &Schema{}
+	}
+	return prop.coerce(raw)
+}
+
+func (s *Schema) coerce(raw string) (any, error) {
+	types := s.Types()
+	if len(types) == 0 {
+		// untyped: take json if it parses, otherwise the plain string
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err == nil {
+			return v, nil
+		}
+		return raw, nil
+	}
+
+	var errs []string
+	for _, t := range types {
+		v, err := coerceAs(t, raw, s.Items)
+		if err == nil {
+			return v, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, errors.New(strings.Join(errs, "; "))
+}
+
+func coerceAs(typ, raw string, items *Schema) (any, error) {
+	trimmed := strings.TrimSpace(raw)
+
+	switch typ {
+	case "string":
+		return raw, nil
+
+	case "integer":
+		n, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", trimmed)
+		}
+		return n, nil
+
+	case "number":
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", trimmed)
+		}
+		return f, nil
+
+	case "boolean":
+		switch strings.ToLower(trimmed) {
+		case "true", "yes", "y", "1", "on":
+			return true, nil
+		case "false", "no", "n", "0", "off":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", trimmed)
+
+	case "null":
+		if trimmed == "" || trimmed == "null" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%q is not null", trimmed)
+
+	case "array":
+		if strings.HasPrefix(trimmed, "[") {
+			var v []any
+			if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+				return nil, fmt.Errorf("invalid json array: %w", err)
+			}
+			return v, nil
+		}
+		// one element per line
+		if items == nil {
+			items = &Schema{}
+		}
+		values := []any{}
+		for _, line := range strings.Split(raw, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			v, err := items.coerce(line)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+
+	case "object":
+		var v map[string]any
+		if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+			return nil, fmt.Errorf("invalid json object: %w", err)
+		}
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %q", typ)
+}
+
+// BuildArgs coerces every raw value and reports all failures at once.
+func (s *Schema) BuildArgs(raw map[string]string) (map[string]any, error) {
+	args := make(map[string]any, len(raw))
+	var errs []string
+
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v, err := s.Coerce(name, raw[name])
+		if err != nil {
+			errs = append(errs, name+": "+err.Error())
+			continue
+		}
+		args[name] = v
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+	return args, nil
+}
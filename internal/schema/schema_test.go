@@ -0,0 +1,149 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const issueSchema = `{
+	"type": "object",
+	"properties": {
+		"repo": {"type": "string"},
+		"state": {"type": "string", "enum": ["open", "closed", "all"]},
+		"labels": {"type": "array", "items": {"type": "string", "enum": ["bug", "build"]}},
+		"limit": {"type": ["integer", "null"]}
+	},
+	"required": ["repo"]
+}`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(json.RawMessage(issueSchema))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	names := s.PropertyNames()
+	want := []string{"labels", "limit", "repo", "state"}
+	if len(names) != len(want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, names)
+		}
+	}
+
+	if types := s.Properties["limit"].Types(); len(types) != 2 || types[0] != "integer" {
+		t.Errorf("unexpected limit types: %v", types)
+	}
+	if types := s.Properties["repo"].Types(); len(types) != 1 || types[0] != "string" {
+		t.Errorf("unexpected repo types: %v", types)
+	}
+}
+
+func TestParseEmpty(t *testing.T) {
+	s, err := Parse(nil)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(s.PropertyNames()) != 0 {
+		t.Errorf("expected no properties")
+	}
+}
+
+func TestEnumValues(t *testing.T) {
+	s, _ := Parse(json.RawMessage(issueSchema))
+
+	if got := s.EnumValues("state", "c"); len(got) != 1 || got[0] != "closed" {
+		t.Errorf("expected [closed], got %v", got)
+	}
+	if got := s.EnumValues("state", ""); len(got) != 3 {
+		t.Errorf("expected all states, got %v", got)
+	}
+	if got := s.EnumValues("labels", "bu"); len(got) != 2 {
+		t.Errorf("expected item enum values, got %v", got)
+	}
+	if got := s.EnumValues("repo", ""); len(got) != 0 {
+		t.Errorf("expected no values for free text, got %v", got)
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	s, _ := Parse(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"owner": {"type": "string"},
+			"count": {"type": "integer"},
+			"ratio": {"type": "number"},
+			"draft": {"type": "boolean"},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"ids": {"type": "array", "items": {"type": "integer"}},
+			"filter": {"type": "object"},
+			"limit": {"type": ["integer", "null"]}
+		}
+	}`))
+
+	tests := []struct {
+		prop string
+		raw  string
+		want string
+	}{
+		{"owner", "octocat\n", `"octocat"`},
+		{"owner", "  spaced  \n", `"  spaced  "`},
+		{"count", "42\n", `42`},
+		{"ratio", "0.5", `0.5`},
+		{"draft", "yes\n", `true`},
+		{"draft", "false", `false`},
+		{"tags", "bug\nbuild\n", `["bug","build"]`},
+		{"tags", `["a","b"]`, `["a","b"]`},
+		{"ids", "1\n2\n", `[1,2]`},
+		{"filter", `{"state":"open"}`, `{"state":"open"}`},
+		{"limit", "null", `null`},
+		{"limit", "7", `7`},
+		{"unknown", "3", `3`},
+		{"unknown", "plain text", `"plain text"`},
+	}
+
+	for _, tt := range tests {
+		v, err := s.Coerce(tt.prop, tt.raw)
+		if err != nil {
+			t.Errorf("Coerce(%q, %q): %v", tt.prop, tt.raw, err)
+			continue
+		}
+		got, _ := json.Marshal(v)
+		if string(got) != tt.want {
+			t.Errorf("Coerce(%q, %q) = %s, want %s", tt.prop, tt.raw, got, tt.want)
+		}
+	}
+
+	for _, bad := range []struct{ prop, raw string }{
+		{"count", "many"},
+		{"draft", "maybe"},
+		{"filter", "state=open"},
+		{"ids", "1\nx\n"},
+	} {
+		if _, err := s.Coerce(bad.prop, bad.raw); err == nil {
+			t.Errorf("Coerce(%q, %q): expected error", bad.prop, bad.raw)
+		}
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	s, _ := Parse(json.RawMessage(issueSchema))
+
+	args, err := s.BuildArgs(map[string]string{
+		"repo":  "caffeinum/mcpfs\n",
+		"limit": "10\n",
+	})
+	if err != nil {
+		t.Fatalf("build args: %v", err)
+	}
+	if args["repo"] != "caffeinum/mcpfs" || args["limit"] != int64(10) {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	_, err = s.BuildArgs(map[string]string{"limit": "ten"})
+	if err == nil {
+		t.Fatal("expected coercion error")
+	}
+}
@@ -0,0 +1,217 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// FormatViolations renders violations as a list an agent can act on.
+func FormatViolations(violations []Violation) string {
+	var b strings.Builder
+	b.WriteString("invalid arguments:\n")
+	for _, v := range violations {
+		b.WriteString("  - " + v.String() + "\n")
+	}
+	return b.String()
+}
+
+// Validate checks args against the schema: required properties, types,
+// enums and additionalProperties, recursing into objects and arrays.
+func (s *Schema) Validate(args map[string]any) []Violation {
+	var out []Violation
+	if args == nil {
+		args = map[string]any{}
+	}
+	s.validate("", args, &out)
+	return out
+}
+
+func (s *Schema) validate(path string, v any, out *[]Violation) {
+	if types := s.Types(); len(types) > 0 && !matchesAny(types, v) {
+		*out = append(*out, Violation{
+			Path:    path,
+			Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), typeName(v)),
+		})
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		*out = append(*out, Violation{
+			Path:    path,
+			Message: fmt.Sprintf("must be one of %s", enumList(s.Enum)),
+		})
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		s.validateObject(path, val, out)
+	case []any:
+		if s.Items != nil {
+			for i, item := range val {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, out)
+			}
+		}
+	}
+}
+
+func (s *Schema) validateObject(path string, obj map[string]any, out *[]Violation) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			*out = append(*out, Violation{Path: join(path, name), Message: "required property missing"})
+		}
+	}
+
+	var extra *Schema
+	allowExtra := true
+	if len(s.AdditionalProperties) > 0 {
+		var b bool
+		if err := json.Unmarshal(s.AdditionalProperties, &b); err == nil {
+			allowExtra = b
+		} else {
+			extra = &Schema{}
+			json.Unmarshal(s.AdditionalProperties, extra)
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if prop, ok := s.Properties[k]; ok {
+			prop.validate(join(path, k), obj[k], out)
+			continue
+		}
+		if !allowExtra {
+			*out = append(*out, Violation{Path: join(path, k), Message: "unexpected property" + suggest(k, s.PropertyNames())})
+			continue
+		}
+		if extra != nil {
+			extra.validate(join(path, k), obj[k], out)
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// suggest points at a known property when an unexpected one differs only by
+// case or separators, which is the usual typo.
+func suggest(name string, known []string) string {
+	norm := func(s string) string {
+		return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+	}
+	for _, k := range known {
+		if norm(k) == norm(name) {
+			return fmt.Sprintf(" (did you mean %q?)", k)
+		}
+	}
+	return ""
+}
+
+func matchesAny(types []string, v any) bool {
+	for _, t := range types {
+		if matchesType(t, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, v any) bool {
+	switch t {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "integer":
+		switch n := v.(type) {
+		case float64:
+			return n == math.Trunc(n)
+		case int, int64:
+			return true
+		}
+		return false
+	case "number":
+		switch v.(type) {
+		case float64, int, int64:
+			return true
+		}
+		return false
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	case "array":
+		_, ok := v.([]any)
+		return ok
+	case "object":
+		_, ok := v.(map[string]any)
+		return ok
+	}
+	return true
+}
+
+func typeName(v any) string {
+	switch n := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if n == math.Trunc(n) {
+			return "integer"
+		}
+		return "number"
+	case int, int64:
+		return "integer"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func inEnum(enum []any, v any) bool {
+	got, _ := json.Marshal(v)
+	for _, e := range enum {
+		want, _ := json.Marshal(e)
+		if string(got) == string(want) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []any) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		data, _ := json.Marshal(e)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const createIssueSchema = `{
+	"type": "object",
+	"properties": {
+		"repo": {"type": "string"},
+		"title": {"type": "string"},
+		"state": {"type": "string", "enum": ["open", "closed"]},
+		"count": {"type": "integer"},
+		"labels": {"type": "array", "items": {"type": "string"}},
+		"meta": {
+			"type": "object",
+			"properties": {"milestone": {"type": "integer"}},
+			"additionalProperties": false
+		}
+	},
+	"required": ["repo", "title"],
+	"additionalProperties": false
+}`
+
+func parseArgs(t *testing.T, data string) map[string]any {
+	t.Helper()
+	var args map[string]any
+	if err := json.Unmarshal([]byte(data), &args); err != nil {
+		t.Fatalf("parse args: %v", err)
+	}
+	return args
+}
+
+func TestValidateOK(t *testing.T) {
+	s, _ := Parse(json.RawMessage(createIssueSchema))
+	args := parseArgs(t, `{"repo":"a/b","title":"x","state":"open","count":3,"labels":["bug"],"meta":{"milestone":2}}`)
+
+	if v := s.Validate(args); len(v) != 0 {
+		t.Errorf("expected no violations, got %v", v)
+	}
+}
+
+func TestValidateViolations(t *testing.T) {
+	s, _ := Parse(json.RawMessage(createIssueSchema))
+	args := parseArgs(t, `{"repo":5,"state":"merged","count":1.5,"labels":["bug",2],"meta":{"due":"x"},"Title":"x"}`)
+
+	got := make(map[string]string)
+	for _, v := range s.Validate(args) {
+		got[v.Path] = v.Message
+	}
+
+	want := map[string]string{
+		"title":     "required property missing",
+		"repo":      "expected string, got integer",
+		"state":     `must be one of "open", "closed"`,
+		"count":     "expected integer, got number",
+		"labels[1]": "expected string, got integer",
+		"meta.due":  "unexpected property",
+		"Title":     `unexpected property (did you mean "title"?)`,
+	}
+	for path, msg := range want {
+		if got[path] != msg {
+			t.Errorf("%s: expected %q, got %q", path, msg, got[path])
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d violations, got %v", len(want), got)
+	}
+}
+
+func TestValidateNilArgs(t *testing.T) {
+	s, _ := Parse(json.RawMessage(createIssueSchema))
+	if v := s.Validate(nil); len(v) != 2 {
+		t.Errorf("expected 2 missing required properties, got %v", v)
+	}
+
+	empty, _ := Parse(nil)
+	if v := empty.Validate(nil); len(v) != 0 {
+		t.Errorf("expected no violations for empty schema, got %v", v)
+	}
+}
+
+func TestFormatViolations(t *testing.T) {
+	out := FormatViolations([]Violation{
+		{Path: "repo", Message: "required property missing"},
+		{Message: "expected object, got array"},
+	})
+	if !strings.Contains(out, "  - repo: required property missing\n") {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if !strings.Contains(out, "  - expected object, got array\n") {
+		t.Errorf("unexpected output: %q", out)
+	}
+}